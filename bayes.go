@@ -0,0 +1,367 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ematvey/gostat"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Bayesian linear regression with the conjugate Normal-Inverse-Gamma prior
+//
+// β | σ^2 ~ N(m0, σ^2 V0),		σ^2 ~ InvGamma(a0, b0)
+//
+// has a posterior of the same form, with
+//
+// Vn = (V0^-1 + XtX)^-1
+// mn = Vn (V0^-1 m0 + Xt y)
+// an = a0 + n/2
+// bn = b0 + (yt y + m0t V0^-1 m0 - mnt Vn^-1 mn) / 2
+//
+// Integrating out σ^2, each coefficient follows a Student t with 2an degrees
+// of freedom, location mn_j and scale sqrt(bn/an Vn_jj), and a new response at
+// x is t with location x mn and scale sqrt(bn/an (1 + x Vn xt)).
+type Bayes struct {
+	mean  []float64
+	cov   *mat64.Dense // Vn
+	shape float64
+	rate  float64
+}
+
+// Predict returns the posterior mean of the response at x.
+func (b *Bayes) Predict(x []float64) float64 {
+	return b.mean[0] + sum(prod(x, b.mean[1:]))
+}
+
+// PredictiveDistribution returns the posterior predictive distribution of the
+// response at x.
+func (b *Bayes) PredictiveDistribution(x []float64) StudentT {
+	row := append([]float64{1}, x...)
+	v := 1.0
+	for i, xi := range row {
+		for j, xj := range row {
+			v += xi * b.cov.At(i, j) * xj
+		}
+	}
+	return StudentT{
+		Location: b.Predict(x),
+		Scale:    math.Sqrt(b.rate / b.shape * v),
+		Df:       2 * b.shape,
+	}
+}
+
+// A StudentT is a location-scale Student t distribution.
+type StudentT struct {
+	Location, Scale, Df float64
+}
+
+// Mean returns the mean, or NaN if Df <= 1.
+func (t StudentT) Mean() float64 {
+	if t.Df <= 1 {
+		return math.NaN()
+	}
+	return t.Location
+}
+
+// Variance returns the variance, or +Inf if Df <= 2.
+func (t StudentT) Variance() float64 {
+	if t.Df <= 2 {
+		return math.Inf(1)
+	}
+	return t.Scale * t.Scale * t.Df / (t.Df - 2)
+}
+
+// LogPDF returns the log density at x.
+func (t StudentT) LogPDF(x float64) float64 {
+	z := (x - t.Location) / t.Scale
+	a, _ := math.Lgamma((t.Df + 1) / 2)
+	b, _ := math.Lgamma(t.Df / 2)
+	return a - b - 0.5*math.Log(t.Df*math.Pi) - math.Log(t.Scale) -
+		(t.Df+1)/2*math.Log1p(z*z/t.Df)
+}
+
+// Quantile returns the p-th quantile.
+func (t StudentT) Quantile(p float64) float64 {
+	return t.Location + t.Scale*tQuantile(p, t.Df)
+}
+
+// Interval returns the central 1 - alpha interval.
+func (t StudentT) Interval(alpha float64) [2]float64 {
+	return [2]float64{t.Quantile(alpha / 2), t.Quantile(1 - alpha/2)}
+}
+
+// tQuantile returns the p-th quantile of the standard t with nu degrees of
+// freedom, by bisection on P(|T| <= q) = F_CDF(1, nu)(q^2).
+func tQuantile(p, nu float64) float64 {
+	if p <= 0 || p >= 1 {
+		return math.Copysign(math.Inf(1), p-0.5)
+	}
+	target := math.Abs(2*p - 1)
+	if target == 0 {
+		return 0
+	}
+	cdf := stat.F_CDF(1, nu)
+	lo, hi := 0.0, 1.0
+	for cdf(hi*hi) < target {
+		lo, hi = hi, 2*hi
+	}
+	for i := 0; i < 100 && hi-lo > 1e-12*hi; i++ {
+		mid := (lo + hi) / 2
+		if cdf(mid*mid) < target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return math.Copysign((lo+hi)/2, p-0.5)
+}
+
+// NIGPrior is a Normal-Inverse-Gamma prior on the coefficients, intercept
+// first, and the error variance.
+type NIGPrior struct {
+	Mean       []float64  // m0; 0 if nil
+	Covariance *DataFrame // V0, positive definite, in units of σ^2
+	Shape      float64    // a0 > 0
+	Rate       float64    // b0 > 0
+}
+
+// NewNIGPrior returns a prior for p coefficients (the intercept included)
+// centered at 0, with covariance scale^2 σ^2 I and σ^2 ~ InvGamma(shape,
+// rate).
+func NewNIGPrior(p int, scale, shape, rate float64) *NIGPrior {
+	cov := make([][]float64, p)
+	for i := range cov {
+		cov[i] = make([]float64, p)
+		cov[i][i] = scale * scale
+	}
+	return &NIGPrior{
+		Covariance: NewDataFrame(cov),
+		Shape:      shape,
+		Rate:       rate,
+	}
+}
+
+type bayesTrainer struct {
+	prior *NIGPrior
+}
+
+// NewBayesTrainer returns a Trainer for Bayesian linear regression under the
+// prior. If prior is nil, a weak prior is used, NewNIGPrior(p, 100, 0.01,
+// 0.01); it is only weak for data of moderate scale.
+func NewBayesTrainer(prior *NIGPrior) Trainer {
+	return &bayesTrainer{
+		prior: prior,
+	}
+}
+
+// BayesSummary summarizes the posterior of a Bayesian linear regression. The
+// coefficients are the posterior means.
+type BayesSummary struct {
+	betas     []float64
+	residuals []float64
+	fitted    []float64
+	response  []float64
+	vn        *mat64.Dense
+	shape     float64
+	rate      float64
+	logML     float64
+	data      *DataFrame
+}
+
+func (b BayesSummary) Data() *DataFrame        { return b.data }
+func (b BayesSummary) Coefficients() []float64 { return b.betas }
+func (b BayesSummary) Residuals() []float64    { return b.residuals }
+func (b BayesSummary) Yhat() []float64         { return b.fitted }
+func (b BayesSummary) Response() []float64     { return b.response }
+
+func (b BayesSummary) SumOfSquares() float64 {
+	return sum(prod(b.residuals, b.residuals))
+}
+
+// PosteriorCovariance returns the posterior covariance of the coefficients,
+// bn / (an - 1) Vn, which is finite for an > 1.
+func (b BayesSummary) PosteriorCovariance() *DataFrame {
+	cov := &mat64.Dense{}
+	cov.Scale(b.rate/(b.shape-1), b.vn)
+	return Mat64ToDF(cov)
+}
+
+// Marginal returns the posterior distribution of the jth coefficient.
+func (b BayesSummary) Marginal(j int) StudentT {
+	return StudentT{
+		Location: b.betas[j],
+		Scale:    math.Sqrt(b.rate / b.shape * b.vn.At(j, j)),
+		Df:       2 * b.shape,
+	}
+}
+
+// CredibleIntervals returns the central 1 - alpha posterior interval of each
+// coefficient.
+func (b BayesSummary) CredibleIntervals(alpha float64) [][2]float64 {
+	ci := make([][2]float64, len(b.betas))
+	for j := range ci {
+		ci[j] = b.Marginal(j).Interval(alpha)
+	}
+	return ci
+}
+
+// SigmaShape and SigmaRate return the parameters an, bn of the inverse gamma
+// posterior of σ^2.
+func (b BayesSummary) SigmaShape() float64 { return b.shape }
+func (b BayesSummary) SigmaRate() float64  { return b.rate }
+
+// SigmaMean returns the posterior mean of σ^2, bn / (an - 1).
+func (b BayesSummary) SigmaMean() float64 { return b.rate / (b.shape - 1) }
+
+// LogMarginalLikelihood returns log p(y), the evidence for comparing models:
+//
+// -n/2 log(2π) + 1/2 log(|Vn| / |V0|) + a0 log b0 -
+// an log bn + log Γ(an) - log Γ(a0)
+func (b BayesSummary) LogMarginalLikelihood() float64 { return b.logML }
+
+// Posterior returns the posterior as a prior, for updating with more data.
+func (b BayesSummary) Posterior() *NIGPrior {
+	return &NIGPrior{
+		Mean:       append([]float64(nil), b.betas...),
+		Covariance: Mat64ToDF(mat64.DenseCopyOf(b.vn)),
+		Shape:      b.shape,
+		Rate:       b.rate,
+	}
+}
+
+func (b BayesSummary) String() string {
+	return fmt.Sprintf(`
+		Posterior mean:
+		%v
+		95%% credible intervals:
+		%v
+
+		Posterior mean of sigma^2: %v
+		Log marginal likelihood: %v`,
+		roundAll(b.betas),
+		b.CredibleIntervals(0.05),
+		round(b.SigmaMean(), 4),
+		round(b.logML, 3),
+	)
+}
+
+func (t *bayesTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	data := withIntercept(x)
+	p := data.Cols()
+
+	prior := t.prior
+	if prior == nil {
+		prior = NewNIGPrior(p, 100, 0.01, 0.01)
+	}
+	if prior.Covariance == nil || prior.Covariance.Rows() != p || prior.Covariance.Cols() != p {
+		return nil, nil, DimensionError
+	}
+	if !(prior.Shape > 0 && prior.Rate > 0) {
+		return nil, nil, fmt.Errorf("prior shape and rate must be positive")
+	}
+	m0 := prior.Mean
+	if m0 == nil {
+		m0 = rep(0.0, p)
+	}
+	if len(m0) != p {
+		return nil, nil, DimensionError
+	}
+
+	// V0^-1, and log|V0|, from the Cholesky factor of V0
+	l0, err := cholesky(prior.Covariance.Data())
+	if err != nil {
+		return nil, nil, err
+	}
+	p0 := choleskyInverse(l0)
+
+	// Vn^-1 = V0^-1 + XtX
+	X := data.Data()
+	pn := &mat64.Dense{}
+	pn.Mul(X.T(), X)
+	pn.Add(pn, p0)
+	ln, err := cholesky(pn)
+	if err != nil {
+		return nil, nil, err
+	}
+	vn := choleskyInverse(ln)
+
+	// mn = Vn (V0^-1 m0 + Xt y), solved with the Cholesky factor of Vn^-1
+	rhs := &mat64.Dense{}
+	rhs.Mul(X.T(), mat64.NewDense(n, 1, y))
+	pm := &mat64.Dense{}
+	pm.Mul(p0, mat64.NewDense(p, 1, m0))
+	rhs.Add(rhs, pm)
+	mean := backSolve(ln, forwardSolve(ln, mat64.Col(nil, 0, rhs)))
+
+	shape := prior.Shape + float64(n)/2
+	rate := prior.Rate + (dot(y, y)+dot(m0, mat64.Col(nil, 0, pm))-dot(mean, mat64.Col(nil, 0, rhs)))/2
+
+	// log|Vn| - log|V0| = -log|Vn^-1| - log|V0|
+	logDet := 0.0
+	for i := 0; i < p; i++ {
+		logDet -= 2 * (math.Log(ln[i][i]) + math.Log(l0[i][i]))
+	}
+	ga, _ := math.Lgamma(shape)
+	g0, _ := math.Lgamma(prior.Shape)
+	logML := -float64(n)/2*math.Log(2*math.Pi) + logDet/2 +
+		prior.Shape*math.Log(prior.Rate) - shape*math.Log(rate) + ga - g0
+
+	response := make([]float64, n)
+	copy(response, y)
+	fitted, residuals := linearFit(x, mean, response)
+
+	return &Bayes{
+		mean:  mean,
+		cov:   vn,
+		shape: shape,
+		rate:  rate,
+	}, BayesSummary{
+		betas:     mean,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		vn:        vn,
+		shape:     shape,
+		rate:      rate,
+		logML:     logML,
+		data:      data,
+	}, nil
+}
+
+// backSolve returns L^-T v for lower triangular L.
+func backSolve(l [][]float64, v []float64) []float64 {
+	x := make([]float64, len(v))
+	for i := len(v) - 1; i >= 0; i-- {
+		s := v[i]
+		for k := i + 1; k < len(v); k++ {
+			s -= l[k][i] * x[k]
+		}
+		x[i] = s / l[i][i]
+	}
+	return x
+}
+
+// choleskyInverse returns (LLt)^-1 = L^-T L^-1, which is symmetric by
+// construction.
+func choleskyInverse(l [][]float64) *mat64.Dense {
+	p := len(l)
+	inv := mat64.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		e := make([]float64, p)
+		e[j] = 1
+		inv.SetCol(j, backSolve(l, forwardSolve(l, e)))
+	}
+	for i := 0; i < p; i++ {
+		for j := 0; j < i; j++ {
+			v := (inv.At(i, j) + inv.At(j, i)) / 2
+			inv.Set(i, j, v)
+			inv.Set(j, i, v)
+		}
+	}
+	return inv
+}
@@ -0,0 +1,83 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// a nearly flat prior gives back least squares
+func TestBayesDiffuse(t *testing.T) {
+	prior := NewNIGPrior(4, 1e6, 1e-6, 1e-6)
+	_, summary, err := NewBayesTrainer(prior).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, ols.Coefficients(), summary.Coefficients(), 1e-4)
+
+	// σ^2 ~ InvGamma(n/2, RSS/2)
+	bs := summary.(BayesSummary)
+	n := float64(len(y))
+	assertNear(t, []float64{ols.SumOfSquares() / (n - 2)}, []float64{bs.SigmaMean()}, 1e-4)
+
+	for j, ci := range bs.CredibleIntervals(0.05) {
+		assert.Equal(t, true, ci[0] < bs.Coefficients()[j] && bs.Coefficients()[j] < ci[1])
+	}
+}
+
+// updating with all the data at once is the same as updating with each half
+// in turn, and the evidence factors as p(y1) p(y2 | y1)
+func TestBayesSequential(t *testing.T) {
+	prior := NewNIGPrior(4, 10, 2, 3)
+	_, all, err := NewBayesTrainer(prior).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	_, first, err := NewBayesTrainer(prior).Train(NewDataFrame(data[:10]), y[:10])
+	assert.Equal(t, nil, err)
+	_, second, err := NewBayesTrainer(first.(BayesSummary).Posterior()).Train(NewDataFrame(data[10:]), y[10:])
+	assert.Equal(t, nil, err)
+
+	a, b := all.(BayesSummary), second.(BayesSummary)
+	assertNear(t, a.Coefficients(), b.Coefficients(), 1e-8)
+	assertNear(t, []float64{a.SigmaShape(), a.SigmaRate()}, []float64{b.SigmaShape(), b.SigmaRate()}, 1e-8)
+	for i := 0; i < 4; i++ {
+		assertNear(t, a.PosteriorCovariance().GetRow(i), b.PosteriorCovariance().GetRow(i), 1e-10)
+	}
+	assertNear(t,
+		[]float64{a.LogMarginalLikelihood()},
+		[]float64{first.(BayesSummary).LogMarginalLikelihood() + b.LogMarginalLikelihood()},
+		1e-8)
+}
+
+// the evidence of one more row is its predictive density
+func TestBayesPredictive(t *testing.T) {
+	prior := NewNIGPrior(4, 10, 2, 3)
+	model, summary, err := NewBayesTrainer(prior).Train(NewDataFrame(data[:20]), y[:20])
+	assert.Equal(t, nil, err)
+
+	pred := model.(*Bayes).PredictiveDistribution(data[20])
+	_, next, err := NewBayesTrainer(summary.(BayesSummary).Posterior()).Train(NewDataFrame(data[20:]), y[20:])
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{pred.LogPDF(y[20])}, []float64{next.(BayesSummary).LogMarginalLikelihood()}, 1e-10)
+
+	assert.Equal(t, model.Predict(data[20]), pred.Mean())
+	ci := pred.Interval(0.1)
+	assertNear(t, []float64{pred.Location}, []float64{(ci[0] + ci[1]) / 2}, 1e-8)
+}
+
+func TestTQuantile(t *testing.T) {
+	// qt(0.975, c(1, 5, 30))
+	assertNear(t, []float64{12.7062047, 2.5705818, 2.0422725},
+		[]float64{tQuantile(0.975, 1), tQuantile(0.975, 5), tQuantile(0.975, 30)}, 1e-6)
+	assert.Equal(t, 0.0, tQuantile(0.5, 3))
+	assertNear(t, []float64{-tQuantile(0.9, 4)}, []float64{tQuantile(0.1, 4)}, 1e-12)
+
+	// the density integrates to the cdf
+	st := StudentT{Location: 1, Scale: 2, Df: 3}
+	area, h := 0.0, 1e-3
+	for x := st.Quantile(0.25); x < st.Quantile(0.75); x += h {
+		area += math.Exp(st.LogPDF(x+h/2)) * h
+	}
+	assertNear(t, []float64{0.5}, []float64{area}, 1e-3)
+}
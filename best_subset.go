@@ -0,0 +1,236 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Subset is the best model of a given size found by BestSubsets.
+type Subset struct {
+	Columns     []int   // columns of the DataFrame in the model
+	RSS         float64 // residual sum of squares
+	AdjRSquared float64 // adjusted r-squared
+	Cp          float64 // Mallows' Cp
+	BIC         float64 // n log(RSS/n) + (k + 1) log(n)
+}
+
+// BestSubsets finds, for every size k = 1 .. maxSize, the k columns giving
+// the least squares fit (with intercept) with the smallest RSS. If maxSize is
+// not positive, every size up to min(p, n-2) is searched.
+//
+// Rather than refitting every combination, the search works on the
+// triangular factor R of the QR decomposition of [X y] (centered), where the
+// RSS of a model is the square of the last diagonal element. Dropping a
+// column from a model only needs a few Givens rotations to restore R, and
+// since removing columns never decreases the RSS, the RSS of a model bounds
+// the RSS of all of its subsets: a branch is skipped once it cannot improve
+// on the best model of any size it contains (Furnival & Wilson's leaps and
+// bounds).
+//
+// Cp uses sigma^2 from the full model, so it is NaN unless n > p + 1.
+func BestSubsets(x *DataFrame, y []float64, maxSize int) ([]Subset, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if maxSize <= 0 || maxSize > p {
+		maxSize = p
+	}
+	if maxSize > n-2 {
+		maxSize = n - 2
+	}
+	if maxSize < 1 {
+		return nil, fmt.Errorf("not enough rows for subset selection")
+	}
+
+	r := centeredR(x, y)
+	b := &leaps{
+		rss:  rep(math.Inf(1), p+1),
+		best: make([][]int, p+1),
+		max:  maxSize,
+	}
+	vars := make([]int, p)
+	for j := range vars {
+		vars[j] = j
+	}
+
+	// seed the bounds with backward elimination, then search
+	b.record(vars, r)
+	b.eliminate(vars, r)
+	b.search(vars, r, make([]bool, p))
+
+	tss := dot(subtractMean(y), subtractMean(y))
+	sigma2 := math.NaN()
+	if n > p+1 {
+		sigma2 = b.rss[p] / float64(n-p-1)
+	}
+
+	subsets := make([]Subset, maxSize)
+	for k := 1; k <= maxSize; k++ {
+		cols := append([]int(nil), b.best[k]...)
+		sort.Ints(cols)
+		rss := b.rss[k]
+		nf, kf := float64(n), float64(k)
+		subsets[k-1] = Subset{
+			Columns:     cols,
+			RSS:         rss,
+			AdjRSquared: 1 - (rss/(nf-kf-1))/(tss/(nf-1)),
+			Cp:          rss/sigma2 - nf + 2*(kf+1),
+			BIC:         nf*math.Log(rss/nf) + (kf+1)*math.Log(nf),
+		}
+	}
+	return subsets, nil
+}
+
+// centeredR returns the (p+1) x (p+1) upper triangular factor of the
+// centered [X y], with rows as slices.
+func centeredR(x *DataFrame, y []float64) [][]float64 {
+	n, p := x.Rows(), x.Cols()
+	a := mat64.NewDense(n, p+1, nil)
+	for j := 0; j < p; j++ {
+		a.SetCol(j, subtractMean(x.GetCol(j)))
+	}
+	a.SetCol(p, subtractMean(y))
+
+	qr := &mat64.QR{}
+	qr.Factorize(a)
+	rm := &mat64.Dense{}
+	rm.RFromQR(qr)
+
+	// with fewer rows than columns, the missing rows of R are zero
+	rows, _ := rm.Dims()
+	r := make([][]float64, p+1)
+	for i := range r {
+		r[i] = make([]float64, p+1)
+		if i < rows {
+			copy(r[i], mat64.Row(nil, i, rm))
+		}
+	}
+	return r
+}
+
+// dropColumn removes column i from the upper triangular r and restores its
+// triangular form with Givens rotations.
+func dropColumn(r [][]float64, i int) [][]float64 {
+	m := len(r)
+	out := make([][]float64, m)
+	for k := range r {
+		out[k] = make([]float64, 0, m-1)
+		out[k] = append(out[k], r[k][:i]...)
+		out[k] = append(out[k], r[k][i+1:]...)
+	}
+
+	for j := i; j < m-1; j++ {
+		a, b := out[j][j], out[j+1][j]
+		if b == 0 {
+			continue
+		}
+		h := math.Hypot(a, b)
+		c, s := a/h, b/h
+		for k := j; k < m-1; k++ {
+			u, v := out[j][k], out[j+1][k]
+			out[j][k] = c*u + s*v
+			out[j+1][k] = -s*u + c*v
+		}
+	}
+	return out[:m-1]
+}
+
+type leaps struct {
+	rss  []float64 // best RSS by model size
+	best [][]int   // columns of the best model by size
+	max  int       // largest model size of interest
+}
+
+func (b *leaps) record(vars []int, r [][]float64) float64 {
+	last := len(r) - 1
+	rss := r[last][last] * r[last][last]
+	if k := len(vars); rss < b.rss[k] {
+		b.rss[k] = rss
+		b.best[k] = append([]int(nil), vars...)
+	}
+	return rss
+}
+
+// eliminate greedily drops the column that raises the RSS least until none
+// are left, giving an upper bound on the best RSS of every size.
+func (b *leaps) eliminate(vars []int, r [][]float64) {
+	for len(vars) > 1 {
+		bestRSS, bestI := math.Inf(1), 0
+		var bestR [][]float64
+		for i := range vars {
+			child := dropColumn(r, i)
+			last := len(child) - 1
+			if rss := child[last][last] * child[last][last]; rss < bestRSS {
+				bestRSS, bestI, bestR = rss, i, child
+			}
+		}
+		vars = append(append([]int(nil), vars[:bestI]...), vars[bestI+1:]...)
+		r = bestR
+		b.record(vars, r)
+	}
+}
+
+// search visits every subset of vars that keeps the fixed columns. At each
+// node the free columns are ordered by how much dropping them raises the RSS,
+// most first: the child that drops the most important column has the most
+// columns still free, and also the largest RSS, so it is the most likely to be
+// pruned.
+func (b *leaps) search(vars []int, r [][]float64, fixed []bool) {
+	type child struct {
+		i   int
+		r   [][]float64
+		rss float64
+	}
+
+	var children []child
+	for i, v := range vars {
+		if fixed[v] || len(vars) == 1 {
+			continue
+		}
+		cr := dropColumn(r, i)
+		last := len(cr) - 1
+		children = append(children, child{i: i, r: cr, rss: cr[last][last] * cr[last][last]})
+	}
+	sort.Slice(children, func(a, c int) bool { return children[a].rss > children[c].rss })
+
+	nfixed := 0
+	for _, v := range vars {
+		if fixed[v] {
+			nfixed++
+		}
+	}
+
+	fixed = append([]bool(nil), fixed...)
+	for _, c := range children {
+		cvars := append(append([]int(nil), vars[:c.i]...), vars[c.i+1:]...)
+		b.record(cvars, c.r)
+
+		// the subsets of the child have between nfixed and len(cvars) - 1
+		// columns, and none can have a smaller RSS than the child
+		if b.improvable(c.rss, nfixed, len(cvars)-1) {
+			b.search(cvars, c.r, fixed)
+		}
+
+		// later children keep this column
+		fixed[vars[c.i]] = true
+		nfixed++
+	}
+}
+
+// improvable reports whether rss beats the best model of any size from lo to
+// hi.
+func (b *leaps) improvable(rss float64, lo, hi int) bool {
+	if hi > b.max {
+		hi = b.max
+	}
+	for k := hi; k >= lo && k >= 1; k-- {
+		if rss < b.rss[k] {
+			return true
+		}
+	}
+	return false
+}
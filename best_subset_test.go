@@ -0,0 +1,62 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestBestSubsets(t *testing.T) {
+	subsets, err := BestSubsets(NewDataFrame(data), y, 0)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(subsets))
+	assert.Equal(t, []int{0}, subsets[0].Columns)
+	assert.Equal(t, []int{0, 1}, subsets[1].Columns)
+
+	// the full model matches least squares, with Cp = p + 1
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, round(ols.SumOfSquares(), 6), round(subsets[2].RSS, 6))
+	assert.Equal(t, 4.0, round(subsets[2].Cp, 6))
+	assert.Equal(t, 0.898, round(subsets[2].AdjRSquared, 3)) // summary(lm(stack.loss ~ ., stackloss)) in R
+}
+
+func TestBestSubsetsExhaustive(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	n, p := 40, 8
+	rows := make([][]float64, n)
+	resp := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		for j := range rows[i] {
+			rows[i][j] = rnd.NormFloat64()
+		}
+		resp[i] = rows[i][1] - 2*rows[i][4] + 0.5*rows[i][6] + rnd.NormFloat64()
+	}
+	df := NewDataFrame(rows)
+
+	subsets, err := BestSubsets(df, resp, 0)
+	assert.Equal(t, nil, err)
+
+	// compare against refitting every combination
+	best := rep(math.Inf(1), p+1)
+	for mask := 1; mask < 1<<uint(p); mask++ {
+		var cols []int
+		for j := 0; j < p; j++ {
+			if mask&(1<<uint(j)) != 0 {
+				cols = append(cols, j)
+			}
+		}
+		sub, err := df.SelectCols(cols...)
+		assert.Equal(t, nil, err)
+		_, summary, err := NewOlsTrainer().Train(sub, resp)
+		assert.Equal(t, nil, err)
+		best[len(cols)] = math.Min(best[len(cols)], summary.SumOfSquares())
+	}
+	for k := 1; k <= p; k++ {
+		assert.Equal(t, round(best[k], 6), round(subsets[k-1].RSS, 6))
+	}
+	assert.Equal(t, []int{1, 4, 6}, subsets[2].Columns)
+}
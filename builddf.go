@@ -70,6 +70,9 @@ func (d *DataFrame) Data() *mat64.Dense {
 	return mat64.DenseCopyOf(d.X)
 }
 
+// Labels returns the column names of the DataFrame, or nil if none were given.
+func (d *DataFrame) Labels() []string { return d.labels }
+
 func (d *DataFrame) Copy() *DataFrame {
 	return &DataFrame{
 		X: d.Data(),
@@ -152,6 +155,30 @@ func (d *DataFrame) PushRow(row []float64) error {
 	return nil
 }
 
+// SelectCols returns a new DataFrame holding a copy of the given columns, in
+// the order given. Column labels are carried over.
+func (d *DataFrame) SelectCols(cols ...int) (*DataFrame, error) {
+	if len(cols) == 0 {
+		return nil, DimensionError
+	}
+
+	x := mat64.NewDense(d.n, len(cols), nil)
+	var labels []string
+	for j, col := range cols {
+		if col < 0 || col >= d.c {
+			return nil, DimensionError
+		}
+		x.SetCol(j, d.GetCol(col))
+		if len(d.labels) == d.c {
+			labels = append(labels, d.labels[col])
+		}
+	}
+
+	df := Mat64ToDF(x)
+	df.labels = labels
+	return df, nil
+}
+
 // RemoveCol removes a specified column from the Dataframe.
 func (d *DataFrame) RemoveCol(col int) error {
 	if col > d.c {
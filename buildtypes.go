@@ -10,6 +10,14 @@ type Model interface {
 	Predict(x []float64) float64
 }
 
+// A Classifier is a Model for a categorical response. Predict returns the
+// most probable class.
+type Classifier interface {
+	Model
+	Probabilities(x []float64) []float64
+	Classify(x []float64) float64
+}
+
 // summarize the model
 type Summary interface {
 	Data() *DataFrame
@@ -19,3 +27,11 @@ type Summary interface {
 	Response() []float64
 	SumOfSquares() float64
 }
+
+// a Summary of a fit with observation weights, var(y_i) = σ^2 / w_i. The
+// diagnostics work with the rows of X and the residuals scaled by sqrt(w),
+// which gives the weighted hat matrix W^1/2 X (Xt W X)^-1 Xt W^1/2
+type WeightedSummary interface {
+	Summary
+	Weights() []float64
+}
@@ -100,7 +100,7 @@ func standardize(x []float64) []float64 {
 	dev := sd(x)
 
 	cp := make([]float64, len(x))
-	copy(x, cp)
+	copy(cp, x)
 
 	for i := 0; i < len(cp); i++ {
 		cp[i] -= m
@@ -212,3 +212,69 @@ func seq(start, end, by int) []int {
 	}
 	return s
 }
+
+func dot(x, y []float64) float64 {
+	d := 0.0
+	for i := range x {
+		d += x[i] * y[i]
+	}
+	return d
+}
+
+// scaleCols centers each column of the DataFrame and scales it to unit
+// standard deviation, returning the columns along with the means and standard
+// deviations used. Constant columns are centered but left unscaled.
+func scaleCols(df *DataFrame) (cols [][]float64, means, sds []float64) {
+	p := df.Cols()
+	cols = make([][]float64, p)
+	means = make([]float64, p)
+	sds = make([]float64, p)
+
+	for j := 0; j < p; j++ {
+		col := df.GetCol(j)
+		means[j] = mean(col)
+		sds[j] = sd(col)
+		if sds[j] == 0 {
+			sds[j] = 1
+		}
+		for i := range col {
+			col[i] = (col[i] - means[j]) / sds[j]
+		}
+		cols[j] = col
+	}
+	return cols, means, sds
+}
+
+// unscaleBetas maps coefficients fit against standardized columns and a
+// centered response back onto the original scale. The intercept is returned
+// as the first value.
+func unscaleBetas(b, means, sds []float64, ybar float64) []float64 {
+	betas := make([]float64, len(b)+1)
+	betas[0] = ybar
+	for j := range b {
+		betas[j+1] = b[j] / sds[j]
+		betas[0] -= betas[j+1] * means[j]
+	}
+	return betas
+}
+
+// linearFit returns the fitted values and residuals of y for the given
+// coefficients, where betas[0] is the intercept.
+func linearFit(df *DataFrame, betas, y []float64) (fitted, residuals []float64) {
+	n := df.Rows()
+	fitted = make([]float64, n)
+	residuals = make([]float64, n)
+	for i := 0; i < n; i++ {
+		fitted[i] = betas[0] + dot(df.GetRow(i), betas[1:])
+		residuals[i] = y[i] - fitted[i]
+	}
+	return fitted, residuals
+}
+
+// withIntercept returns a copy of the DataFrame with a leading column of ones,
+// matching the design matrix the diagnostics expect from Summary.Data().
+func withIntercept(df *DataFrame) *DataFrame {
+	d := df.Copy()
+	d.PushCol(rep(1., d.Rows()))
+	return d
+}
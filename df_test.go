@@ -1,6 +1,7 @@
 package glasso
 
 import (
+	"math"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -93,6 +94,15 @@ func assertEqual(t *testing.T, x, y []float64) {
 	}
 }
 
+func assertNear(t *testing.T, x, y []float64, tol float64) {
+	assert.Equal(t, len(x), len(y))
+	for i := range x {
+		if math.Abs(x[i]-y[i]) > tol {
+			t.Errorf("index %d: %v and %v differ by more than %v", i, x[i], y[i], tol)
+		}
+	}
+}
+
 func TestApplyDF(t *testing.T) {
 	t.Parallel()
 
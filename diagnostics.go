@@ -14,7 +14,7 @@ import (
 // D_{i} = \frac{r_{i}^2}{p * MSE} * \frac{h_{ii}}{(1 - h_{ii})^2}
 func CooksDistance(m Summary) []float64 {
 	h := LeveragePoints(m)
-	residuals := m.Residuals()
+	residuals := scaledResiduals(m)
 	distances := make([]float64, m.Data().Rows())
 	p := float64(m.Data().Cols())
 	mse := MseAdjusted(m)
@@ -49,6 +49,32 @@ func MseAdjusted(m Summary) float64 {
 	return m.SumOfSquares() / float64(m.Data().Rows()-m.Data().Cols())
 }
 
+// designMatrix returns the design matrix of the model, with the rows scaled
+// by sqrt(w) for a WeightedSummary.
+func designMatrix(m Summary) *mat64.Dense {
+	x := m.Data().Data()
+	if ws, ok := m.(WeightedSummary); ok {
+		x.Apply(func(i, _ int, v float64) float64 {
+			return math.Sqrt(ws.Weights()[i]) * v
+		}, x)
+	}
+	return x
+}
+
+// scaledResiduals returns the residuals of the model, scaled by sqrt(w) for a
+// WeightedSummary.
+func scaledResiduals(m Summary) []float64 {
+	ws, ok := m.(WeightedSummary)
+	if !ok {
+		return m.Residuals()
+	}
+	r := make([]float64, len(m.Residuals()))
+	for i, e := range m.Residuals() {
+		r[i] = math.Sqrt(ws.Weights()[i]) * e
+	}
+	return r
+}
+
 // LeveragePoints returns the diagonal of the hat matrix
 // H = X(X'X)^-1X'  , X = QR,  X' = R'Q'
 //   = QR(R'Q'QR)-1 R'Q'
@@ -57,12 +83,13 @@ func MseAdjusted(m Summary) float64 {
 //	 = QQ' (the first p cols of Q, where X = n x p)
 //
 // Leverage points are considered large if they exceed 2p/ n
-
+//
+// For a WeightedSummary, X is replaced by W^1/2 X.
 func LeveragePoints(m Summary) []float64 {
 	q := &mat64.Dense{}
 	h := &mat64.Dense{}
 	qr := &mat64.QR{}
-	qr.Factorize(m.Data().X)
+	qr.Factorize(designMatrix(m))
 	q.QFromQR(qr)
 
 	// get the first p columns of Q
@@ -88,12 +115,14 @@ func LeveragePoints(m Summary) []float64 {
 //
 // t_{i} = \frac{\hat{\epsilon}}{\sigma * \sqrt{1 - h_{ii}}}
 // \hat{\epsilon} =
+//
+// For a WeightedSummary, the residuals are scaled by sqrt(w).
 func StudentizedResiduals(m Summary) []float64 {
 	n, c := m.Data().Rows(), m.Data().Cols()
 	sigma := math.Sqrt(m.SumOfSquares() / float64(n-c))
 	h := LeveragePoints(m)
 	t := make([]float64, n)
-	residuals := m.Residuals()
+	residuals := scaledResiduals(m)
 	for i := 0; i < m.Data().Rows(); i++ {
 		t[i] = residuals[i] / (sigma * math.Sqrt(1-h[i]))
 	}
@@ -118,10 +147,12 @@ func Press(m Summary) []float64 {
 // defined as sigma*(XtX)-1
 // Using QR decomposition: X = QR
 // ((QR)tQR)-1 ---> (RtQtQR)-1 ---> (RtR)-1 ---> R-1Rt-1 --> sigma*R-1Rt-1
+//
+// For a WeightedSummary, X is replaced by W^1/2 X, giving sigma*(XtWX)-1.
 func VarCov(m Summary) (*DataFrame, error) {
 	r := &mat64.Dense{}
 	qr := &mat64.QR{}
-	qr.Factorize(m.Data().X)
+	qr.Factorize(designMatrix(m))
 	r.RFromQR(qr)
 
 	var rinv mat64.Dense
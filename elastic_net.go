@@ -0,0 +1,222 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// The elastic net mixes the lasso and ridge penalties:
+//
+// min( 1/2n \sum(y_i - \beta_0 - \sum x_ij * \beta_j)^2 + \lambda \sum (\alpha |\beta_j| + (1 - \alpha)/2 \beta_j^2) )
+//
+// alpha = 1 is the lasso, alpha = 0 is ridge regression.
+type ElasticNet struct {
+	betas []float64
+}
+
+func (e *ElasticNet) Predict(x []float64) float64 {
+	return e.betas[0] + sum(prod(x, e.betas[1:]))
+}
+
+// ElasticNetTrainer fits the elastic net over a decreasing sequence of
+// lambdas, from lambda_max (the smallest lambda at which every coefficient is
+// 0) down to ratio * lambda_max, using the previous solution as a warm start.
+type ElasticNetTrainer struct {
+	alpha   float64
+	nlambda int
+	ratio   float64
+}
+
+// NewElasticNetTrainer returns a trainer for the elastic net path with
+// nlambda values of lambda. If nlambda or ratio are not positive, 100 values
+// and a ratio of 1e-4 (0.01 when there are more columns than rows) are used.
+func NewElasticNetTrainer(alpha float64, nlambda int, ratio float64) *ElasticNetTrainer {
+	return &ElasticNetTrainer{
+		alpha:   alpha,
+		nlambda: nlambda,
+		ratio:   ratio,
+	}
+}
+
+// Train fits the whole path and returns the model at the smallest lambda.
+func (e *ElasticNetTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	path, err := e.Path(x, y)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, summary := path.Fit(len(path.lambdas) - 1)
+	return model, summary, nil
+}
+
+// Path fits the elastic net for every lambda in the sequence. Within each
+// lambda, coordinate descent only cycles over the columns that survive the
+// sequential strong rule
+//
+// |x_j' r| / n >= \alpha (2 \lambda_k - \lambda_{k-1})
+//
+// and the KKT conditions are checked on the discarded columns afterwards,
+// adding any violators back in.
+func (e *ElasticNetTrainer) Path(x *DataFrame, y []float64) (*ElasticNetPath, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if e.alpha < 0 || e.alpha > 1 {
+		return nil, fmt.Errorf("alpha must be between 0 and 1")
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	cols, means, sds := scaleCols(x)
+	xx := colSquares(cols)
+	ybar := mean(y)
+	r := subtractMean(y)
+	tss := dot(r, r)
+	tol := DefaultTolerance * tss / float64(n)
+	lambdas := e.lambdaSequence(cols, r, n, p)
+
+	path := &ElasticNetPath{
+		lambdas:  lambdas,
+		betas:    make([][]float64, len(lambdas)),
+		df:       make([]int, len(lambdas)),
+		devRatio: make([]float64, len(lambdas)),
+		data:     x.Copy(),
+		response: response,
+	}
+
+	b := rep(0.0, p)
+	grad := make([]float64, p)
+	prev := lambdas[0]
+	for k, lambda := range lambdas {
+		for j := range cols {
+			grad[j] = math.Abs(dot(cols[j], r)) / float64(n)
+		}
+
+		// screen with the strong rule, keeping anything already in the model
+		strong := make([]bool, p)
+		for j := range cols {
+			strong[j] = b[j] != 0 || grad[j] >= e.alpha*(2*lambda-prev)
+		}
+
+		for {
+			active := make([]int, 0, p)
+			for j, ok := range strong {
+				if ok {
+					active = append(active, j)
+				}
+			}
+			coordinateDescent(cols, xx, r, b, lambda, e.alpha, active, DefaultMaxIterations, tol)
+
+			// columns left out must satisfy |x_j' r| / n <= \alpha \lambda
+			violated := false
+			for j := range cols {
+				if !strong[j] && math.Abs(dot(cols[j], r))/float64(n) > e.alpha*lambda {
+					strong[j] = true
+					violated = true
+				}
+			}
+			if !violated {
+				break
+			}
+		}
+
+		path.betas[k] = unscaleBetas(b, means, sds, ybar)
+		for _, v := range b {
+			if v != 0 {
+				path.df[k]++
+			}
+		}
+		path.devRatio[k] = 1 - dot(r, r)/tss
+		prev = lambda
+	}
+
+	return path, nil
+}
+
+// lambda_max = max_j |x_j' y| / (n \alpha), with the sequence spaced evenly on
+// the log scale down to ratio * lambda_max.
+func (e *ElasticNetTrainer) lambdaSequence(cols [][]float64, y []float64, n, p int) []float64 {
+	nlambda, ratio := e.nlambda, e.ratio
+	if nlambda <= 0 {
+		nlambda = 100
+	}
+	if ratio <= 0 {
+		ratio = 1e-4
+		if p > n {
+			ratio = 0.01
+		}
+	}
+
+	// lambda_max is infinite for ridge, so borrow a small alpha like glmnet
+	alpha := math.Max(e.alpha, 1e-3)
+	lmax := 0.0
+	for _, col := range cols {
+		lmax = math.Max(lmax, math.Abs(dot(col, y))/(float64(n)*alpha))
+	}
+
+	lambdas := make([]float64, nlambda)
+	for k := range lambdas {
+		if nlambda == 1 {
+			lambdas[k] = lmax
+			break
+		}
+		lambdas[k] = lmax * math.Pow(ratio, float64(k)/float64(nlambda-1))
+	}
+	return lambdas
+}
+
+// ElasticNetPath holds the solutions along the regularization path.
+type ElasticNetPath struct {
+	lambdas  []float64
+	betas    [][]float64
+	df       []int
+	devRatio []float64
+	data     *DataFrame
+	response []float64
+}
+
+// Lambdas returns the decreasing lambda sequence of the path.
+func (e *ElasticNetPath) Lambdas() []float64 { return e.lambdas }
+
+// Coefficients returns the coefficients at the ith lambda, on the original
+// scale and with the intercept first.
+func (e *ElasticNetPath) Coefficients(i int) []float64 { return e.betas[i] }
+
+// Df returns the number of nonzero coefficients at each lambda.
+func (e *ElasticNetPath) Df() []int { return e.df }
+
+// DevianceExplained returns the fraction of the null deviance (1 - RSS/TSS)
+// explained at each lambda.
+func (e *ElasticNetPath) DevianceExplained() []float64 { return e.devRatio }
+
+// Fit returns the model and summary at the ith lambda of the path.
+func (e *ElasticNetPath) Fit(i int) (Model, Summary) {
+	betas := e.betas[i]
+	fitted, residuals := linearFit(e.data, betas, e.response)
+
+	return &ElasticNet{
+		betas: betas,
+	}, OlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  e.response,
+		n:         e.data.Rows(),
+		p:         e.data.Cols(),
+		data:      withIntercept(e.data),
+	}
+}
+
+// Select returns the model and summary at the lambda on the path closest to
+// the one given, on the log scale.
+func (e *ElasticNetPath) Select(lambda float64) (Model, Summary) {
+	best := 0
+	dist := func(l float64) float64 { return math.Abs(math.Log(l / lambda)) }
+	for i, l := range e.lambdas {
+		if dist(l) < dist(e.lambdas[best]) {
+			best = i
+		}
+	}
+	return e.Fit(best)
+}
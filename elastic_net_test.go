@@ -0,0 +1,49 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestElasticNetPath(t *testing.T) {
+	trainer := NewElasticNetTrainer(1, 50, 1e-4)
+	path, err := trainer.Path(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 50, len(path.Lambdas()))
+
+	// nothing enters the model at lambda_max
+	assert.Equal(t, 0, path.Df()[0])
+	assertEqual(t, path.Coefficients(0), []float64{mean(y), 0, 0, 0})
+	assert.Equal(t, 0.0, path.DevianceExplained()[0])
+
+	// the lasso end of the path is close to least squares
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, path.Df()[49])
+	assertEqual(t, path.Coefficients(49), ols.Coefficients())
+
+	// deviance explained only grows as lambda shrinks
+	dev := path.DevianceExplained()
+	for i := 1; i < len(dev); i++ {
+		assert.T(t, dev[i] >= dev[i-1]-1e-9)
+	}
+
+	// selecting a lambda gives the same fit as the lasso trainer
+	lambda := path.Lambdas()[20]
+	_, selected := path.Select(lambda)
+	_, lasso, err := NewLassoTrainer(lambda).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertEqual(t, selected.Coefficients(), lasso.Coefficients())
+}
+
+func TestElasticNetRidge(t *testing.T) {
+	_, summary, err := NewElasticNetTrainer(0, 10, 0.01).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for _, b := range summary.Coefficients()[1:] {
+		assert.NotEqual(t, 0.0, b)
+	}
+
+	_, _, err = NewElasticNetTrainer(2, 10, 0.01).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+}
@@ -1,20 +1,26 @@
 package glasso
 
 import (
-	"sort"
-
-	"github.com/gonum/matrix/mat64"
+	"fmt"
+	"math"
 )
 
-type fsTrainer struct {
-	delta   float64
-	epsilon float64
+// StagewiseTrainer fits incremental forward stagewise regression.
+type StagewiseTrainer struct {
+	delta    float64
+	epsilon  float64
+	maxSteps int
 }
 
-func NewForwardStageWiseTrainer(delta, epsilon float64) Trainer {
-	return &fsTrainer{
-		delta:   delta,
-		epsilon: epsilon,
+// NewForwardStageWiseTrainer returns a forward stagewise trainer taking steps
+// of size epsilon until no predictor has absolute correlation with the
+// residuals above delta, or maxSteps steps have been taken. If maxSteps is not
+// positive, 10000 steps are allowed.
+func NewForwardStageWiseTrainer(delta, epsilon float64, maxSteps int) *StagewiseTrainer {
+	return &StagewiseTrainer{
+		delta:    delta,
+		epsilon:  epsilon,
+		maxSteps: maxSteps,
 	}
 }
 
@@ -26,85 +32,125 @@ func (r *fsModel) Predict(x []float64) float64 {
 	return r.betas[0] + sum(prod(x, r.betas[1:]))
 }
 
-func calculateCorrelation(x *mat64.Dense, y []float64) []float64 {
-	_, p := x.Dims()
-	cors := make([]float64, 0, p)
-	for i := 0; i < p; i++ {
-		cors[i] = cor(mat64.Col(nil, i, x), y)
+// calculateCorrelation returns the correlation of each column of x with y.
+func calculateCorrelation(x [][]float64, y []float64) []float64 {
+	cors := make([]float64, len(x))
+	for i, col := range x {
+		cors[i] = cor(col, y)
 	}
 	return cors
 }
 
+// Train fits the path and returns the model at its last step.
+func (f *StagewiseTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
+	path, err := f.Path(df, y)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, summary := path.Fit(path.Steps() - 1)
+	return model, summary, nil
+}
+
 // Start with initial residual r = y, and β1 = β2 = · · · = βp = 0.
 // Find the predictor Zj (j = 1, . . . , p) most correlated with r
 // Update βj ← βj + δj
 // Set r ← r − δjZj
 // Repeat
 //
-// Pretty much the same as least squares boosting
-func (f *fsTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
-	// first we need to standardize the matrix and scale y
-	// and set up variables
-	df.Standardize() // make sure x_j_bar = 0
+// where δj = ε · sign(<r, Zj>). Pretty much the same as least squares
+// boosting; as ε -> 0 the path approaches the lasso path.
+func (f *StagewiseTrainer) Path(df *DataFrame, y []float64) (*StagewisePath, error) {
 	n, p := df.Rows(), df.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if f.epsilon <= 0 {
+		return nil, fmt.Errorf("epsilon must be positive")
+	}
+
+	maxSteps := f.maxSteps
+	if maxSteps <= 0 {
+		maxSteps = 10000
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	// standardize the columns and center y, so the intercept is ybar
+	x, means, sds := scaleCols(df)
+	ybar := mean(y)
+	r := subtractMean(y)
 
 	// set all betas to 0
 	betas := rep(0.0, p)
 
-	// center y
-	r := subtractMean(y) // make sure y_bar = 0
-	x := mat64.NewDense(n, p, rep(0.0, n*p))
-	data := df.Data()
-	firstRun := true
-
-	// we continue until the residuals are uncorrelated with the predictors up
-	// to a certain delta
-	isCorrelation := func(y []float64) bool {
-		if firstRun {
-			firstRun = false
-			return true
+	path := &StagewisePath{
+		data:     df.Copy(),
+		response: response,
+	}
+	path.betas = append(path.betas, unscaleBetas(betas, means, sds, ybar))
+
+	for step := 0; step < maxSteps; step++ {
+		// we continue until the residuals are uncorrelated with the
+		// predictors up to a certain delta
+		if sd(r) == 0 {
+			break
+		}
+		// a constant column has no correlation, NaN, and is never chosen
+		cors := calculateCorrelation(x, r)
+		j := -1
+		for i, c := range cors {
+			if !math.IsNaN(c) && (j < 0 || math.Abs(c) > math.Abs(cors[j])) {
+				j = i
+			}
+		}
+		if j < 0 || math.Abs(cors[j]) < f.delta {
+			break
 		}
 
-		// find the most correlated variable
-		cors := calculateCorrelation(data, y)
-		if max(cors) < f.delta {
-			return false
+		// beta_j = beta_j + delta_j
+		delta := f.epsilon * sign(cors[j])
+		betas[j] += delta
+
+		// r = r - delta_j * x_j
+		for i, v := range x[j] {
+			r[i] -= delta * v
 		}
-		return true
+
+		path.betas = append(path.betas, unscaleBetas(betas, means, sds, ybar))
 	}
 
-	// how do we know when to stop?
-	for isCorrelation(r) {
+	return path, nil
+}
 
-		// find the most correlated variable
-		cors := calculateCorrelation(data, y)
-		maxCor := max(cors)
-		maxIdx := sort.SearchFloat64s(cors, maxCor)
+// StagewisePath holds the coefficients after every forward stagewise step.
+type StagewisePath struct {
+	betas    [][]float64
+	data     *DataFrame
+	response []float64
+}
 
-		// update beta_j
-		// beta_j = beta_j + delta_j
-		// where delta_j = epsilon * sign(y, x_j)
-		x.SetCol(maxIdx, mat64.Col(nil, maxIdx, data))
-		//ols := NewOLS(&DataFrame{x, n, p, nil})
-		//ols.Train(r)
+// Steps returns the number of steps taken, plus one for the empty model.
+func (s *StagewisePath) Steps() int { return len(s.betas) }
 
-		// update beta
-		delta := f.epsilon * sign(sum(prod(mat64.Col(nil, maxIdx, x), r)))
-		betas[maxIdx] += delta
+// Coefficients returns the coefficients after the ith step, on the original
+// scale and with the intercept first.
+func (s *StagewisePath) Coefficients(i int) []float64 { return s.betas[i] }
 
-		// set r = r - delta_j * x_j
-		r = diff(r, multSlice(mat64.Col(nil, maxIdx, x), delta))
-	}
+// Fit returns the model and summary after the ith step.
+func (s *StagewisePath) Fit(i int) (Model, Summary) {
+	betas := s.betas[i]
+	fitted, residuals := linearFit(s.data, betas, s.response)
 
 	return &fsModel{
-			betas: betas,
-		}, OlsSummary{
-			data: df,
-			n:    n,
-			p:    p,
-			// fitted:    fitted,
-			// residuals: residuals,
-			// response:  response,
-			betas: betas,
-		}, nil
+		betas: betas,
+	}, OlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  s.response,
+		n:         s.data.Rows(),
+		p:         s.data.Cols(),
+		data:      withIntercept(s.data),
+	}
 }
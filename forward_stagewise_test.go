@@ -0,0 +1,44 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestForwardStagewise(t *testing.T) {
+	// the budget is respected
+	path, err := NewForwardStageWiseTrainer(0, 0.01, 50).Path(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 51, path.Steps())
+	assertEqual(t, path.Coefficients(0), []float64{mean(y), 0, 0, 0})
+
+	// with enough small steps the fit approaches least squares
+	trainer := NewForwardStageWiseTrainer(0.001, 0.005, 0)
+	model, summary, err := trainer.Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.T(t, summary.SumOfSquares() < 1.05*ols.SumOfSquares())
+
+	assert.Equal(t, len(y), len(summary.Yhat()))
+	assertNear(t, summary.Yhat(), diff(y, summary.Residuals()), 1e-9)
+	assert.Equal(t, round(model.Predict(data[0]), 6), round(summary.Yhat()[0], 6))
+}
+
+// a constant first column is skipped rather than ending the path
+func TestForwardStagewiseConstantColumn(t *testing.T) {
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = append([]float64{1}, row...)
+	}
+
+	path, err := NewForwardStageWiseTrainer(0, 0.01, 50).Path(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 51, path.Steps())
+
+	last := path.Coefficients(path.Steps() - 1)
+	assert.Equal(t, 0.0, last[1])
+	assert.T(t, last[2] != 0)
+}
@@ -0,0 +1,575 @@
+package gam
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/timkaye11/glasso"
+)
+
+// A generalized additive model relates the mean of the response to a sum of
+// smooth functions of the columns,
+//
+// g(mu) = b0 + f_1(x_1) + ... + f_p(x_p)
+//
+// where each f_j is a penalized regression spline (see smooth) and g is the
+// link of a glasso.Family. The coefficients minimize the penalized deviance
+//
+// D(beta) + sum_j lambda_j beta_j' P_j beta_j
+//
+// by penalized iteratively reweighted least squares (PIRLS): with working
+// weights w = (dmu/deta)^2 / V(mu) and working response
+// z = eta + (y - mu) / (dmu/deta), each step solves
+//
+// beta = (X'WX + S)^-1 X'Wz,		S = blockdiag(lambda_j P_j)
+//
+// Unless they are given, the smoothing parameters are chosen at every step to
+// minimize the generalized cross-validation score of the working model,
+//
+// GCV = n ||W^1/2 (z - X beta)||^2 / (n - tr(A))^2,	A = X (X'WX + S)^-1 X'W
+//
+// one lambda at a time over a grid, until none changes (Gu's performance
+// iteration). For the gaussian family with the identity link this is the
+// usual GCV for penalized least squares. tr(A) is the effective degrees of
+// freedom of the model, and its diagonal, summed over each term, that of the
+// term.
+//
+// Standard errors come from the Bayesian posterior covariance of the
+// coefficients, (X'WX + S)^-1 phi, with phi the dispersion (Wood, 2006).
+type GAM struct {
+	intercept float64
+	smooths   []*smooth
+	coef      [][]float64
+	offsets   []int
+	cov       *mat64.Dense
+	link      glasso.Link
+}
+
+// Predict returns the fitted mean of the response at x.
+func (g *GAM) Predict(x []float64) float64 {
+	return g.link.InverseFn(g.LinearPredictor(x))
+}
+
+// LinearPredictor returns b0 + sum_j f_j(x_j).
+func (g *GAM) LinearPredictor(x []float64) float64 {
+	eta := g.intercept
+	for j, s := range g.smooths {
+		eta += dot(s.basis(x[j]), g.coef[j])
+	}
+	return eta
+}
+
+// Smooth returns f_j, the term of the jth column, at each of x, with its
+// pointwise standard error. The terms are centered to sum to zero over the
+// data they were fit on.
+func (g *GAM) Smooth(j int, x []float64) (fit, se []float64) {
+	s, off := g.smooths[j], g.offsets[j]
+	fit = make([]float64, len(x))
+	se = make([]float64, len(x))
+	for i, v := range x {
+		b := s.basis(v)
+		fit[i] = dot(b, g.coef[j])
+		variance := 0.0
+		for a, ba := range b {
+			for c, bc := range b {
+				variance += ba * g.cov.At(off+a, off+c) * bc
+			}
+		}
+		se[i] = math.Sqrt(math.Max(variance, 0))
+	}
+	return fit, se
+}
+
+// Config configures the fit of a GAM. Theta is not estimated, so a negative
+// binomial family must be given a positive one.
+type Config struct {
+	F         glasso.Family // distribution of the response; gaussian if unset
+	Basis     int           // B-spline basis functions per column; 10 if not positive
+	Lambdas   []float64     // smoothing parameter of each column; chosen by GCV if nil
+	MaxIt     int           // upper bound on PIRLS iterations; 50 if not positive
+	Tolerance float64       // relative change in deviance at convergence; glasso.DefaultTolerance if not positive
+}
+
+// NewConfig returns a config for the family with the defaults.
+func NewConfig(fam glasso.Family) *Config {
+	return &Config{
+		F: fam,
+	}
+}
+
+type gamTrainer struct {
+	config *Config
+}
+
+// NewTrainer returns a Trainer for a GAM with a smooth of every column.
+func NewTrainer(config *Config) glasso.Trainer {
+	return &gamTrainer{
+		config: config,
+	}
+}
+
+// Summary summarizes a GAM fit. Data returns the model matrix, the intercept
+// column and then the centered basis of each term in turn, so that Data times
+// Coefficients is the linear predictor. Yhat is the fitted mean and Residuals
+// the response residuals.
+type Summary struct {
+	model      *GAM
+	x          *glasso.DataFrame
+	design     *glasso.DataFrame
+	betas      []float64
+	fitted     []float64
+	residuals  []float64
+	response   []float64
+	lambdas    []float64
+	edf        []float64
+	totalEdf   float64
+	gcv        float64
+	deviance   float64
+	dispersion float64
+	iterations int
+	converged  bool
+}
+
+func (s *Summary) Data() *glasso.DataFrame { return s.design }
+func (s *Summary) Coefficients() []float64 { return s.betas }
+func (s *Summary) Residuals() []float64    { return s.residuals }
+func (s *Summary) Yhat() []float64         { return s.fitted }
+func (s *Summary) Response() []float64     { return s.response }
+
+func (s *Summary) SumOfSquares() float64 { return dot(s.residuals, s.residuals) }
+
+// Smooth returns the jth term and its pointwise standard errors at the rows
+// of the data.
+func (s *Summary) Smooth(j int) (fit, se []float64) {
+	return s.model.Smooth(j, s.x.GetCol(j))
+}
+
+// Lambdas returns the smoothing parameter of each term.
+func (s *Summary) Lambdas() []float64 { return s.lambdas }
+
+// Edf returns the effective degrees of freedom of each term.
+func (s *Summary) Edf() []float64 { return s.edf }
+
+// TotalEdf returns the effective degrees of freedom of the model, tr(A),
+// including the intercept.
+func (s *Summary) TotalEdf() float64 { return s.totalEdf }
+
+// GCV returns the GCV score of the final working model.
+func (s *Summary) GCV() float64 { return s.gcv }
+
+// Deviance returns the deviance of the fit.
+func (s *Summary) Deviance() float64 { return s.deviance }
+
+// Dispersion returns the dispersion phi: fixed by the family, or the Pearson
+// statistic divided by n - tr(A).
+func (s *Summary) Dispersion() float64 { return s.dispersion }
+
+// Iterations returns the number of PIRLS iterations run.
+func (s *Summary) Iterations() int { return s.iterations }
+
+// Converged reports whether the change in deviance met the tolerance before
+// the iteration limit. If not, the coefficients are the last iterate.
+func (s *Summary) Converged() bool { return s.converged }
+
+func (s *Summary) String() string {
+	labels := s.x.Labels()
+	terms := ""
+	for j, e := range s.edf {
+		name := fmt.Sprintf("s(x%d)", j)
+		if len(labels) == len(s.edf) {
+			name = fmt.Sprintf("s(%s)", labels[j])
+		}
+		terms += fmt.Sprintf("\n\t\t%s: edf %.3f, lambda %.4g", name, e, s.lambdas[j])
+	}
+	return fmt.Sprintf(`
+		Intercept: %.4f
+		Smooth terms:%s
+
+		Deviance: %.3f
+		Total edf: %.3f
+		GCV: %.4f
+		Dispersion: %.4f
+		Iterations: %d (converged: %v)`,
+		s.betas[0],
+		terms,
+		s.deviance,
+		s.totalEdf,
+		s.gcv,
+		s.dispersion,
+		s.iterations,
+		s.converged,
+	)
+}
+
+// the smoothing parameters searched, relative to the scale of each basis
+var lambdaGrid = func() []float64 {
+	grid := make([]float64, 25)
+	for i := range grid {
+		grid[i] = math.Pow(10, -6+float64(i)/2)
+	}
+	return grid
+}()
+
+// maximum number of halvings of a step that leaves the mean invalid
+const maxHalvings = 30
+
+func (t *gamTrainer) Train(x *glasso.DataFrame, y []float64) (glasso.Model, glasso.Summary, error) {
+	c := *t.config
+	if c.F.VarianceFn == nil {
+		c.F = glasso.Gaussian
+	}
+	if c.Basis <= 0 {
+		c.Basis = 10
+	}
+	if c.MaxIt <= 0 {
+		c.MaxIt = 50
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = glasso.DefaultTolerance
+	}
+
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	for i, v := range y {
+		if !c.F.Accepts(v) {
+			return nil, nil, fmt.Errorf("response %v at row %d is not valid for the %s family", v, i, c.F.Name)
+		}
+	}
+	if c.Basis < 4 {
+		return nil, nil, fmt.Errorf("need at least 4 basis functions, got %d", c.Basis)
+	}
+	if c.Lambdas != nil && len(c.Lambdas) != p {
+		return nil, nil, DimensionError
+	}
+
+	// the model matrix: the intercept, then each term's basis
+	smooths := make([]*smooth, p)
+	offsets := make([]int, p)
+	q := 1
+	for j := range smooths {
+		smooths[j] = newSmooth(x.GetCol(j), c.Basis)
+		offsets[j] = q
+		q += smooths[j].size()
+	}
+	if n <= q {
+		return nil, nil, fmt.Errorf("need more rows than the %d coefficients", q)
+	}
+	X := mat64.NewDense(n, q, nil)
+	for i := 0; i < n; i++ {
+		X.Set(i, 0, 1)
+		row := x.GetRow(i)
+		for j, s := range smooths {
+			for k, b := range s.basis(row[j]) {
+				X.Set(i, offsets[j]+k, b)
+			}
+		}
+	}
+
+	// smoothing parameters, on the scale of the data
+	lambdas := make([]float64, p)
+	grid := make([][]float64, p)
+	for j, s := range smooths {
+		grid[j] = make([]float64, len(lambdaGrid))
+		for g, v := range lambdaGrid {
+			grid[j][g] = v * s.scale
+		}
+		switch {
+		case c.Lambdas != nil:
+			lambdas[j] = c.Lambdas[j]
+		case s.linear:
+			lambdas[j] = 0
+		default:
+			lambdas[j] = grid[j][len(lambdaGrid)/2]
+		}
+	}
+
+	f := c.F
+	link := f.Link
+	mu := make([]float64, n)
+	eta := make([]float64, n)
+	for i, v := range y {
+		mu[i] = v
+		if f.InitFn != nil {
+			mu[i] = f.InitFn(v, 1)
+		}
+		eta[i] = link.LinkFn(mu[i])
+	}
+
+	var (
+		fit       *pirlsStep
+		betas     []float64
+		dev       = math.Inf(1)
+		iters     int
+		converged bool
+	)
+	for iters < c.MaxIt {
+		iters++
+
+		// working weights and response
+		w := make([]float64, n)
+		z := make([]float64, n)
+		for i := range y {
+			d := link.DerivativeFn(eta[i])
+			w[i] = d * d / f.VarianceFn(mu[i])
+			z[i] = eta[i] + (y[i]-mu[i])/d
+		}
+		work := newWorkingModel(X, w, z)
+
+		if c.Lambdas == nil {
+			lambdas = work.selectLambdas(smooths, offsets, grid, lambdas)
+		}
+		var err error
+		fit, err = work.solve(smooths, offsets, lambdas)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// halve the step towards the previous coefficients while the mean
+		// is invalid
+		next := fit.betas
+		var devNew float64
+		for h := 0; ; h++ {
+			etaNew := matVec(X, next)
+			muNew := make([]float64, n)
+			for i, e := range etaNew {
+				muNew[i] = link.InverseFn(e)
+			}
+			devNew = deviance(f, y, muNew)
+			if !math.IsNaN(devNew) && !math.IsInf(devNew, 0) {
+				eta, mu = etaNew, muNew
+				break
+			}
+			if betas == nil || h == maxHalvings {
+				return nil, nil, fmt.Errorf("no valid mean at iteration %d", iters)
+			}
+			for k := range next {
+				next[k] = (next[k] + betas[k]) / 2
+			}
+		}
+		betas = next
+
+		converged = math.Abs(devNew-dev) <= c.Tolerance*(math.Abs(devNew)+0.1)
+		dev = devNew
+		if converged {
+			break
+		}
+	}
+
+	// dispersion and the posterior covariance
+	phi := f.Dispersion
+	if phi <= 0 {
+		pearson := 0.0
+		for i := range y {
+			pearson += (y[i] - mu[i]) * (y[i] - mu[i]) / f.VarianceFn(mu[i])
+		}
+		phi = pearson / (float64(n) - fit.edf)
+	}
+	cov := &mat64.Dense{}
+	cov.Scale(phi, fit.inv)
+
+	model := &GAM{
+		intercept: betas[0],
+		smooths:   smooths,
+		coef:      make([][]float64, p),
+		offsets:   offsets,
+		cov:       cov,
+		link:      link,
+	}
+	edf := make([]float64, p)
+	for j, s := range smooths {
+		off := offsets[j]
+		model.coef[j] = betas[off : off+s.size()]
+		for k := off; k < off+s.size(); k++ {
+			edf[j] += fit.influence[k]
+		}
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+	residuals := make([]float64, n)
+	for i := range y {
+		residuals[i] = y[i] - mu[i]
+	}
+
+	return model, &Summary{
+		model:      model,
+		x:          x.Copy(),
+		design:     glasso.Mat64ToDF(X),
+		betas:      betas,
+		fitted:     mu,
+		residuals:  residuals,
+		response:   response,
+		lambdas:    lambdas,
+		edf:        edf,
+		totalEdf:   fit.edf,
+		gcv:        fit.gcv,
+		deviance:   dev,
+		dispersion: phi,
+		iterations: iters,
+		converged:  converged,
+	}, nil
+}
+
+// deviance returns the deviance of the means, falling back on the Pearson
+// statistic for families without a deviance function.
+func deviance(f glasso.Family, y, mu []float64) float64 {
+	dev := 0.0
+	for i := range y {
+		if math.IsNaN(mu[i]) || math.IsInf(mu[i], 0) {
+			return math.NaN()
+		}
+		if f.DevianceFn != nil {
+			dev += f.DevianceFn(y[i], mu[i])
+		} else {
+			dev += (y[i] - mu[i]) * (y[i] - mu[i]) / f.VarianceFn(mu[i])
+		}
+	}
+	return dev
+}
+
+// workingModel holds the cross-products of a weighted least squares problem,
+// which are all that the fit and its GCV score need.
+type workingModel struct {
+	n    int
+	xtwx *mat64.Dense
+	xtwz []float64
+	ztwz float64
+}
+
+func newWorkingModel(X *mat64.Dense, w, z []float64) *workingModel {
+	n, q := X.Dims()
+	m := &workingModel{
+		n:    n,
+		xtwx: mat64.NewDense(q, q, nil),
+		xtwz: make([]float64, q),
+	}
+	for i := 0; i < n; i++ {
+		row := mat64.Row(nil, i, X)
+		for a, va := range row {
+			if va == 0 {
+				continue
+			}
+			m.xtwz[a] += w[i] * va * z[i]
+			for b := a; b < q; b++ {
+				m.xtwx.Set(a, b, m.xtwx.At(a, b)+w[i]*va*row[b])
+			}
+		}
+		m.ztwz += w[i] * z[i] * z[i]
+	}
+	for a := 0; a < q; a++ {
+		for b := 0; b < a; b++ {
+			m.xtwx.Set(a, b, m.xtwx.At(b, a))
+		}
+	}
+	return m
+}
+
+// pirlsStep is the solution of a working model at given smoothing parameters.
+type pirlsStep struct {
+	betas     []float64
+	inv       *mat64.Dense // (X'WX + S)^-1
+	influence []float64    // diagonal of (X'WX + S)^-1 X'WX
+	edf       float64      // its trace, tr(A)
+	gcv       float64
+}
+
+func (m *workingModel) solve(smooths []*smooth, offsets []int, lambdas []float64) (*pirlsStep, error) {
+	M := mat64.DenseCopyOf(m.xtwx)
+	for j, s := range smooths {
+		off := offsets[j]
+		for a := 0; a < s.size(); a++ {
+			for b := 0; b < s.size(); b++ {
+				M.Set(off+a, off+b, M.At(off+a, off+b)+lambdas[j]*s.penalty.At(a, b))
+			}
+		}
+	}
+	inv := &mat64.Dense{}
+	if err := inv.Inverse(M); err != nil {
+		return nil, err
+	}
+
+	betas := matVec(inv, m.xtwz)
+	F := &mat64.Dense{}
+	F.Mul(inv, m.xtwx)
+	q := len(betas)
+	influence := make([]float64, q)
+	edf := 0.0
+	for k := range influence {
+		influence[k] = F.At(k, k)
+		edf += influence[k]
+	}
+
+	// ||W^1/2 (z - X beta)||^2 = z'Wz - 2 beta'X'Wz + beta'X'WX beta
+	rss := m.ztwz - 2*dot(betas, m.xtwz) + dot(betas, matVec(m.xtwx, betas))
+	gcv := math.Inf(1)
+	if r := float64(m.n) - edf; r > 0 {
+		gcv = float64(m.n) * math.Max(rss, 0) / (r * r)
+	}
+
+	return &pirlsStep{
+		betas:     betas,
+		inv:       inv,
+		influence: influence,
+		edf:       edf,
+		gcv:       gcv,
+	}, nil
+}
+
+// selectLambdas minimizes the GCV score over the grid one term at a time,
+// starting from lambdas, until a pass changes none of them.
+func (m *workingModel) selectLambdas(smooths []*smooth, offsets []int, grid [][]float64, lambdas []float64) []float64 {
+	current := append([]float64(nil), lambdas...)
+	score := func(l []float64) float64 {
+		step, err := m.solve(smooths, offsets, l)
+		if err != nil {
+			return math.Inf(1)
+		}
+		return step.gcv
+	}
+
+	best := score(current)
+	for pass := 0; pass < 10; pass++ {
+		changed := false
+		for j, s := range smooths {
+			if s.linear {
+				continue
+			}
+			for _, l := range grid[j] {
+				if l == current[j] {
+					continue
+				}
+				old := current[j]
+				current[j] = l
+				if v := score(current); v < best*(1-1e-10) {
+					best, changed = v, true
+				} else {
+					current[j] = old
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return current
+}
+
+func matVec(a *mat64.Dense, x []float64) []float64 {
+	r, _ := a.Dims()
+	out := make([]float64, r)
+	for i := range out {
+		out[i] = dot(mat64.Row(nil, i, a), x)
+	}
+	return out
+}
+
+func dot(x, y []float64) float64 {
+	s := 0.0
+	for i, v := range x {
+		s += v * y[i]
+	}
+	return s
+}
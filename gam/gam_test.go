@@ -0,0 +1,241 @@
+package gam
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/timkaye11/glasso"
+)
+
+func TestCubicSpline(t *testing.T) {
+	x := []float64{0, 1, 2.5, 3, 4}
+	y := []float64{0, 1, 6.25, 9, 16}
+
+	knots, coef, err := CubicSpline(x, y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(x)-1, len(coef))
+
+	// each piece interpolates both of its knots, with continuous first and
+	// second derivatives where pieces meet
+	for j, c := range coef {
+		h := knots[j+1] - knots[j]
+		end := c[0] + c[1]*h + c[2]*h*h + c[3]*h*h*h
+		if math.Abs(end-y[j+1]) > 1e-9 {
+			t.Errorf("piece %d ends at %v, want %v", j, end, y[j+1])
+		}
+		if j+1 < len(coef) {
+			next := coef[j+1]
+			if d1 := c[1] + 2*c[2]*h + 3*c[3]*h*h; math.Abs(d1-next[1]) > 1e-9 {
+				t.Errorf("first derivative jumps at knot %d", j+1)
+			}
+			if d2 := c[2] + 3*c[3]*h; math.Abs(d2-next[2]) > 1e-9 {
+				t.Errorf("second derivative jumps at knot %d", j+1)
+			}
+		}
+	}
+	// natural boundary conditions
+	assert.Equal(t, 0.0, coef[0][2])
+	last := coef[len(coef)-1]
+	h := knots[len(knots)-1] - knots[len(knots)-2]
+	if math.Abs(last[2]+3*last[3]*h) > 1e-9 {
+		t.Errorf("second derivative at the end is %v", last[2]+3*last[3]*h)
+	}
+
+	_, _, err = CubicSpline([]float64{1}, []float64{1})
+	assert.Equal(t, DimensionError, err)
+}
+
+func TestBsplines(t *testing.T) {
+	t.Parallel()
+
+	s := newSmooth([]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 8)
+	assert.Equal(t, 7, s.size())
+	for _, v := range []float64{0, 0.3, 2.5, 7.77, 10} {
+		total := 0.0
+		for _, b := range bsplines(v, s.knots) {
+			total += b
+		}
+		if math.Abs(total-1) > 1e-12 {
+			t.Errorf("B-splines at %v sum to %v", v, total)
+		}
+	}
+
+	// a column with few distinct values is linear
+	s = newSmooth([]float64{0, 1, 1, 0, 2}, 8)
+	assert.Equal(t, true, s.linear)
+	assert.Equal(t, 1, s.size())
+}
+
+// y = 1 + sin(x1) + x2^2 + 2 x3 + noise, with x3 binary
+func simulate(n int, seed int64) ([][]float64, []float64) {
+	r := rand.New(rand.NewSource(seed))
+	rows := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range rows {
+		x1 := 2 * math.Pi * r.Float64()
+		x2 := 2*r.Float64() - 1
+		x3 := float64(r.Intn(2))
+		rows[i] = []float64{x1, x2, x3}
+		y[i] = 1 + math.Sin(x1) + x2*x2 + 2*x3 + 0.1*r.NormFloat64()
+	}
+	return rows, y
+}
+
+// centered returns f(x) - mean(f(x)), the truth a smooth estimates.
+func centered(f func(float64) float64, x []float64) []float64 {
+	out := make([]float64, len(x))
+	for i, v := range x {
+		out[i] = f(v)
+	}
+	m := mean(out)
+	for i := range out {
+		out[i] -= m
+	}
+	return out
+}
+
+func TestGaussianGAM(t *testing.T) {
+	t.Parallel()
+
+	rows, y := simulate(300, 1)
+	df := glasso.NewDataFrame(rows)
+
+	model, summary, err := NewTrainer(NewConfig(glasso.Gaussian)).Train(df, y)
+	assert.Equal(t, nil, err)
+	s := summary.(*Summary)
+
+	truth := []func(float64) float64{
+		math.Sin,
+		func(v float64) float64 { return v * v },
+		func(v float64) float64 { return 2 * v },
+	}
+	for j, f := range truth {
+		fit, se := s.Smooth(j)
+		want := centered(f, df.GetCol(j))
+		for i := range fit {
+			if math.Abs(fit[i]-want[i]) > 0.1 {
+				t.Fatalf("term %d at row %d: %v, want %v", j, i, fit[i], want[i])
+			}
+			if !(se[i] > 0 && se[i] < 0.1) {
+				t.Fatalf("term %d at row %d: standard error %v", j, i, se[i])
+			}
+		}
+	}
+
+	// the binary column is a straight line and the others are curved
+	edf := s.Edf()
+	assert.Equal(t, 3, len(edf))
+	if math.Abs(edf[2]-1) > 1e-9 {
+		t.Errorf("linear term has %v degrees of freedom", edf[2])
+	}
+	if edf[0] < 3 || edf[1] < 1.5 {
+		t.Errorf("smooth terms have %v degrees of freedom", edf)
+	}
+	if math.Abs(s.TotalEdf()-1-edf[0]-edf[1]-edf[2]) > 1e-9 {
+		t.Errorf("total edf %v does not add up to %v", s.TotalEdf(), edf)
+	}
+
+	// with the identity link the first iteration gives the fit and the
+	// second only confirms that the deviance stopped changing; the
+	// dispersion estimates the noise variance
+	assert.Equal(t, 2, s.Iterations())
+	assert.Equal(t, true, s.Converged())
+	if math.Abs(math.Sqrt(s.Dispersion())-0.1) > 0.02 {
+		t.Errorf("dispersion %v, want about 0.01", s.Dispersion())
+	}
+
+	// the design times the coefficients gives the fit
+	X := s.Data()
+	assert.Equal(t, len(s.Coefficients()), X.Cols())
+	for i := 0; i < 5; i++ {
+		eta := 0.0
+		for k, b := range s.Coefficients() {
+			eta += X.GetRow(i)[k] * b
+		}
+		if math.Abs(eta-s.Yhat()[i]) > 1e-9 || math.Abs(model.Predict(rows[i])-eta) > 1e-9 {
+			t.Errorf("row %d: linear predictor %v, fitted %v", i, eta, s.Yhat()[i])
+		}
+	}
+}
+
+func TestFixedLambdas(t *testing.T) {
+	t.Parallel()
+
+	rows, y := simulate(200, 2)
+	df := glasso.NewDataFrame(rows)
+
+	// a very heavy penalty leaves only the straight line in each term
+	config := NewConfig(glasso.Gaussian)
+	config.Lambdas = []float64{1e12, 1e12, 1e12}
+	_, summary, err := NewTrainer(config).Train(df, y)
+	assert.Equal(t, nil, err)
+	for j, e := range summary.(*Summary).Edf() {
+		if math.Abs(e-1) > 1e-3 {
+			t.Errorf("term %d has %v degrees of freedom, want 1", j, e)
+		}
+	}
+
+	config.Lambdas = []float64{1}
+	_, _, err = NewTrainer(config).Train(df, y)
+	assert.Equal(t, DimensionError, err)
+}
+
+func TestPoissonGAM(t *testing.T) {
+	t.Parallel()
+
+	// y ~ Poisson(exp(0.5 + sin(x)))
+	r := rand.New(rand.NewSource(3))
+	n := 400
+	rows := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range rows {
+		x := 2 * math.Pi * r.Float64()
+		rows[i] = []float64{x}
+		limit, k, p := math.Exp(-math.Exp(0.5+math.Sin(x))), 0.0, r.Float64()
+		for p > limit {
+			k++
+			p *= r.Float64()
+		}
+		y[i] = k
+	}
+	df := glasso.NewDataFrame(rows)
+
+	model, summary, err := NewTrainer(NewConfig(glasso.Poisson)).Train(df, y)
+	assert.Equal(t, nil, err)
+	s := summary.(*Summary)
+	assert.Equal(t, 1.0, s.Dispersion())
+	if !s.Converged() {
+		t.Errorf("no convergence in %d iterations", s.Iterations())
+	}
+
+	fit, _ := s.Smooth(0)
+	want := centered(math.Sin, df.GetCol(0))
+	for i := range fit {
+		if math.Abs(fit[i]-want[i]) > 0.3 {
+			t.Fatalf("row %d: %v, want %v", i, fit[i], want[i])
+		}
+	}
+	for _, x := range []float64{1, 3, 5} {
+		mu := model.Predict([]float64{x})
+		if truth := math.Exp(0.5 + math.Sin(x)); math.Abs(mu-truth) > 0.3*truth {
+			t.Errorf("mean at %v is %v, want %v", x, mu, truth)
+		}
+	}
+}
+
+func TestResponses(t *testing.T) {
+	t.Parallel()
+
+	// counts are not proportions, so the binomial family rejects them
+	rows, y := simulate(100, 4)
+	_, _, err := NewTrainer(NewConfig(glasso.Binomial)).Train(glasso.NewDataFrame(rows), y)
+	assert.NotEqual(t, nil, err)
+
+	for i := range y {
+		y[i] = float64(i % 2)
+	}
+	_, _, err = NewTrainer(NewConfig(glasso.Binomial)).Train(glasso.NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+}
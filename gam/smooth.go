@@ -0,0 +1,149 @@
+package gam
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// A smooth is the penalized regression spline (P-spline) of one column: a
+// cubic B-spline basis on equally spaced knots over the range of the column,
+// with a penalty on the second differences of neighbouring coefficients
+// (Eilers and Marx, 1996). The penalty does not touch straight lines, so a
+// heavily penalized smooth is linear rather than flat.
+//
+// The B-splines sum to one, so each basis column is centered over the data,
+// which makes sum_i f(x_i) = 0, and the last is dropped, which loses nothing
+// since the penalty ignores constant shifts of the coefficients.
+//
+// A column with fewer than four distinct values gets a linear term instead.
+type smooth struct {
+	linear  bool
+	lo, hi  float64
+	knots   []float64
+	means   []float64    // column means of the basis over the data
+	penalty *mat64.Dense // D'D for the second difference matrix D
+	scale   float64      // puts the penalty on the scale of the basis
+}
+
+const degree = 3
+
+func newSmooth(x []float64, k int) *smooth {
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	distinct := 1
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] != sorted[i-1] {
+			distinct++
+		}
+	}
+
+	s := &smooth{
+		lo: sorted[0],
+		hi: sorted[len(sorted)-1],
+	}
+	if distinct < 4 {
+		s.linear = true
+		s.means = []float64{mean(x)}
+		s.penalty = mat64.NewDense(1, 1, nil)
+		s.scale = 1
+		return s
+	}
+	if k > distinct {
+		k = distinct
+	}
+
+	// k + degree + 1 knots, with degree knots past each end of the range
+	h := (s.hi - s.lo) / float64(k-degree)
+	s.knots = make([]float64, k+degree+1)
+	for i := range s.knots {
+		s.knots[i] = s.lo + float64(i-degree)*h
+	}
+
+	s.means = make([]float64, k)
+	for _, v := range x {
+		for j, b := range bsplines(v, s.knots) {
+			s.means[j] += b / float64(len(x))
+		}
+	}
+
+	// second differences of the first k - 1 coefficients, the last being 0
+	m := k - 1
+	s.penalty = mat64.NewDense(m, m, nil)
+	diff := []float64{1, -2, 1}
+	for r := 0; r < k-2; r++ {
+		for a, va := range diff {
+			for b, vb := range diff {
+				if i, j := r+a, r+b; i < m && j < m {
+					s.penalty.Set(i, j, s.penalty.At(i, j)+va*vb)
+				}
+			}
+		}
+	}
+
+	// scale the penalty to the size of the cross-products of the basis, so a
+	// single grid of smoothing parameters suits every column
+	var xtx, ptr float64
+	for _, v := range x {
+		for _, b := range s.basis(v) {
+			xtx += b * b
+		}
+	}
+	for j := 0; j < m; j++ {
+		ptr += s.penalty.At(j, j)
+	}
+	s.scale = xtx / ptr
+	return s
+}
+
+// size returns the number of coefficients of the smooth.
+func (s *smooth) size() int {
+	if s.linear {
+		return 1
+	}
+	return len(s.means) - 1
+}
+
+// basis returns the centered basis at v. Values outside the range of the data
+// are moved to its nearest end, so the smooth is extrapolated as a constant.
+func (s *smooth) basis(v float64) []float64 {
+	v = math.Min(math.Max(v, s.lo), s.hi)
+	if s.linear {
+		return []float64{v - s.means[0]}
+	}
+	b := bsplines(v, s.knots)
+	out := make([]float64, len(b)-1)
+	for j := range out {
+		out[j] = b[j] - s.means[j]
+	}
+	return out
+}
+
+// bsplines returns the values at x of the cubic B-splines on the knots, by
+// the Cox-de Boor recursion.
+func bsplines(x float64, knots []float64) []float64 {
+	n := len(knots) - 1
+	b := make([]float64, n)
+	for i := 0; i < n; i++ {
+		if knots[i] <= x && x < knots[i+1] {
+			b[i] = 1
+		}
+	}
+	for d := 1; d <= degree; d++ {
+		for i := 0; i < n-d; i++ {
+			left := (x - knots[i]) / (knots[i+d] - knots[i]) * b[i]
+			right := (knots[i+d+1] - x) / (knots[i+d+1] - knots[i+1]) * b[i+1]
+			b[i] = left + right
+		}
+	}
+	return b[:n-degree]
+}
+
+func mean(x []float64) float64 {
+	s := 0.0
+	for _, v := range x {
+		s += v
+	}
+	return s / float64(len(x))
+}
@@ -1,4 +1,4 @@
-package np
+package gam
 
 import "fmt"
 
@@ -6,6 +6,8 @@ var (
 	DimensionError = fmt.Errorf("dimension mismatch")
 )
 
+// Coefficients of one piece of a cubic spline,
+// S_j(x) = a + b(x - x_j) + c(x - x_j)^2 + d(x - x_j)^3, as {a, b, c, d}.
 type Coefficients [4]float64
 
 // Application of the Stone-Weierstrauus Theorem
@@ -16,8 +18,10 @@ type Coefficients [4]float64
 // Satisfies the natural boundary conditions (smoothness conditions)
 // a = x[0], ..., x[n] = b
 // S''(a) = S''(b) = 0
+//
+// x must be increasing; the n pieces are returned along with the knots x.
 func CubicSpline(x, y []float64) ([]float64, []Coefficients, error) {
-	if len(x) != len(y) {
+	if len(x) != len(y) || len(x) < 2 {
 		return nil, nil, DimensionError
 	}
 
@@ -32,11 +36,11 @@ func CubicSpline(x, y []float64) ([]float64, []Coefficients, error) {
 		alpha[i] = (3/h[i])*(y[i+1]-y[i]) - (3/h[i-1])*(y[i]-y[i-1])
 	}
 
-	l := make([]float64, n)
+	l := make([]float64, n+1)
 	l[0] = 1
-	mu := make([]float64, n)
+	mu := make([]float64, n+1)
 	mu[0] = 0
-	z := make([]float64, n)
+	z := make([]float64, n+1)
 	z[0] = 0
 
 	for i := 1; i < n; i++ {
@@ -47,20 +51,20 @@ func CubicSpline(x, y []float64) ([]float64, []Coefficients, error) {
 
 	l[n] = 1
 	z[n] = 0
-	c := make([]float64, n)
+	c := make([]float64, n+1)
 	c[n] = 0
 	b := make([]float64, n)
 	d := make([]float64, n)
 
 	for j := n - 1; j >= 0; j-- {
 		c[j] = z[j] - mu[j]*c[j+1]
-		b[j] = (y[j+1] - y[j]) / (h[j] - h[j]*(c[j+1]+2*c[j])) / 3
+		b[j] = (y[j+1]-y[j])/h[j] - h[j]*(c[j+1]+2*c[j])/3
 		d[j] = (c[j+1] - c[j]) / (3 * h[j])
 	}
 
 	coefficients := make([]Coefficients, n)
 	for i := 0; i < n; i++ {
-		coefficients[i] = Coefficients{y[i], c[i], b[i], d[i]}
+		coefficients[i] = Coefficients{y[i], b[i], c[i], d[i]}
 	}
 
 	return x, coefficients, nil
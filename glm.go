@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/ematvey/gostat"
+	"github.com/gonum/matrix"
 	"github.com/gonum/matrix/mat64"
 )
 
@@ -11,9 +13,11 @@ const DefaultTolerance = .000001
 
 // The GamConfig specifies the desired family, and other model configurations
 type GLMConfig struct {
-	F         Family  // Distribution family for the GLM
-	MaxIt     int64   // upper bound on number of model iterations
-	Tolerance float64 // tolerance for model training
+	F         Family    // Distribution family for the GLM
+	MaxIt     int64     // upper bound on number of model iterations
+	Tolerance float64   // tolerance for model training
+	Weights   []float64 // prior weight of each row; all 1 if nil
+	Offset    []float64 // known term added to the linear predictor of each row; 0 if nil
 }
 
 func NewGLMConfig(fam Family, maxit int64, tol float64) *GLMConfig {
@@ -24,6 +28,30 @@ func NewGLMConfig(fam Family, maxit int64, tol float64) *GLMConfig {
 	}
 }
 
+// priors returns the prior weights and offsets for n rows, filling in the
+// defaults.
+func (c *GLMConfig) priors(n int) ([]float64, []float64, error) {
+	weights, offset := rep(1.0, n), rep(0.0, n)
+	if c.Weights != nil {
+		if len(c.Weights) != n {
+			return nil, nil, DimensionError
+		}
+		for _, w := range c.Weights {
+			if w < 0 {
+				return nil, nil, fmt.Errorf("negative prior weight")
+			}
+		}
+		copy(weights, c.Weights)
+	}
+	if c.Offset != nil {
+		if len(c.Offset) != n {
+			return nil, nil, DimensionError
+		}
+		copy(offset, c.Offset)
+	}
+	return weights, offset, nil
+}
+
 type glmTrainer struct {
 	config *GLMConfig
 }
@@ -34,190 +62,714 @@ func NewGlmTrainer(config *GLMConfig) Trainer {
 	}
 }
 
-// Iterative Re-weighting Least Squares Estimation for Generalized Linear Models
+// Train fits the GLM with an intercept along with a coefficient for each
+// column of the DataFrame.
+//
+// Prior weights scale each row's contribution to the likelihood: for the
+// binomial family, y is the proportion of successes and the weight the number
+// of trials, so with weights a response outside [0, 1] is an error. The offset
+// enters the linear predictor with a fixed coefficient of 1, so a Poisson rate
+// model uses log(exposure) as the offset.
 func (l *glmTrainer) Train(df *DataFrame, b []float64) (Model, Summary, error) {
 	if l.config == nil {
 		return nil, nil, fmt.Errorf("config not set")
 	}
+	if len(b) != df.Rows() {
+		return nil, nil, DimensionError
+	}
+	weights, offset, err := l.config.priors(df.Rows())
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.config.Weights != nil {
+		for i, v := range b {
+			if !l.config.F.Accepts(v) {
+				return nil, nil, fmt.Errorf("response %v at row %d is not valid for the %s family", v, i, l.config.F.Name)
+			}
+		}
+	}
+
+	var (
+		data   = withIntercept(df)
+		config = l.config
+		coef   []float64
+		iters  int
+	)
+	if config.F.estimateTheta {
+		config, coef, iters, err = alternateTheta(config, data.Data(), b, weights, offset)
+	} else {
+		coef, iters, err = irls(config, data.Data(), b, weights, offset)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary, err := newGLMSummary(config, data, b, weights, offset, coef, iters)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &GLM{
+		betas:  coef,
+		family: config.F,
+	}, summary, nil
+}
 
-	A := df.Data()
+// Iterative Re-weighting Least Squares Estimation for Generalized Linear Models
+//
+// At each iteration, with eta = X beta + offset and mu = invLink(eta):
+//
+// z = eta - offset + (y - mu) / gprime		working response
+// w = prior * gprime^2 / variance(mu)		working weights
+// beta = (Xt W X)^-1 Xt W z
+//
+// The weighted least squares step is solved as the ordinary least squares
+// problem min ||sqrt(W) X beta - sqrt(W) z|| through the QR decomposition of
+// sqrt(W) X, so only the n x p scaled copy of X is formed. If a step raises
+// the deviance, or leads to an invalid mean, it is halved until it does not.
+//
+// irls returns the coefficients and the number of iterations run.
+func irls(config *GLMConfig, A *mat64.Dense, b, prior, offset []float64) ([]float64, int, error) {
+	fam := config.F
+	link := fam.Link
 	nrow, ncol := A.Dims()
-	x := mat64.NewDense(ncol, 1, rep(0.0, ncol))
 
-	var i int64
-	var err error
-	for ; i < l.config.MaxIt; i++ {
-		eta := matrixMult(A, x)
-		etaCol := mat64.Col(nil, 0, eta)
+	// the first iteration starts from the family's guess at mu rather than
+	// from beta = 0, which need not give a valid mean
+	eta := make([]float64, nrow)
+	for i, v := range b {
+		eta[i] = link.LinkFn(fam.initial(v, prior[i]))
+	}
+	dev, _ := glmDeviance(fam, b, prior, eta)
+
+	var (
+		x     []float64
+		xw    = mat64.NewDense(nrow, ncol, nil)
+		zw    = mat64.NewDense(nrow, 1, nil)
+		iters int
+	)
+	for i := int64(0); i < config.MaxIt; i++ {
+		iters++
+
+		// scale the rows of X and z by sqrt(w)
+		for r, val := range eta {
+			var (
+				g      = link.InverseFn(val)    // g = invLink(eta)
+				gprime = link.DerivativeFn(val) // gprime = d mu / d eta
+				w      = prior[r] * gprime * gprime / fam.VarianceFn(g)
+				z      = val - offset[r] + (b[r]-g)/gprime
+				sw     = math.Sqrt(w)
+			)
+			for c := 0; c < ncol; c++ {
+				xw.Set(r, c, sw*A.At(r, c))
+			}
+			zw.Set(r, 0, sw*z)
+		}
 
-		var (
-			g      = make([]float64, nrow) // g = invLink(eta)
-			gprime = make([]float64, nrow) // g = derivativeFn(eta)
-			w      = make([]float64, nrow) // w = gprime^2 / variance(g)
-		)
+		qr := &mat64.QR{}
+		qr.Factorize(xw)
+		step := &mat64.Dense{}
+		if err := step.SolveQR(qr, false, zw); err != nil && !illConditioned(err) {
+			return nil, 0, err
+		}
+		xnew := mat64.Col(nil, 0, step)
+
+		// step-halving, on the linear predictor since the starting mu has no
+		// coefficients; it is not a fit either, so the first step need only
+		// give a valid mean
+		etaNew := linearPredictor(A, xnew, offset)
+		devNew, ok := glmDeviance(fam, b, prior, etaNew)
+		h := 0
+		for ; !ok || (x != nil && devNew-dev > 1e-10*(math.Abs(dev)+0.1)); h++ {
+			if h == maxHalvings {
+				if !ok {
+					return nil, 0, fmt.Errorf("irls: no step gives a valid mean")
+				}
+				// the deviance is as small as it gets from here
+				return x, iters, nil
+			}
+			for r := range etaNew {
+				etaNew[r] = (etaNew[r] + eta[r]) / 2
+			}
+			if x != nil {
+				for j := range xnew {
+					xnew[j] = (xnew[j] + x[j]) / 2
+				}
+			}
+			devNew, ok = glmDeviance(fam, b, prior, etaNew)
+		}
 
-		for i, val := range etaCol {
-			g[i] = l.config.F.LinkFn(val)
-			gprime[i] = l.config.F.DerivativeFn(val)
-			w[i] = math.Pow(gprime[i], 2.0) / l.config.F.VarianceFn(g[i])
+		// convergence = sqrt(crossprod(x - xold)) <= tolerance
+		converged := false
+		if x != nil {
+			d := diff(xnew, x)
+			converged = math.Sqrt(dot(d, d)) <= config.Tolerance
 		}
+		eta, dev = etaNew, devNew
+		if x != nil || h == 0 {
+			// a halved first step is only a linear predictor, so the
+			// coefficients wait for a full step
+			x = xnew
+		}
+		if converged {
+			break
+		}
+	}
+	if x == nil {
+		return nil, 0, fmt.Errorf("irls: no full step taken in %d iterations", config.MaxIt)
+	}
+
+	return x, iters, nil
+}
+
+// illConditioned reports whether err only warns that a matrix is close to
+// singular. The solution is still computed, and a poor IRLS step is caught by
+// the step-halving.
+func illConditioned(err error) bool {
+	_, ok := err.(matrix.Condition)
+	return ok
+}
 
-		// z = eta + (b - g) / gprime
-		z := mat64.NewDense(nrow, 1, nil)
-		eta.Clone(z)
-		z.Apply(func(i, j int, eta float64) float64 {
-			return eta + (b[i]-g[i])/gprime[i]
-		}, z)
+// upper bound on the number of times an IRLS step is halved
+const maxHalvings = 30
 
-		// convert w = w * I
-		wMat := mat64.NewDense(nrow, nrow, rep(0.0, nrow*nrow))
-		for i := 0; i < nrow; i++ {
-			wMat.Set(i, i, w[i])
+// linearPredictor returns X beta + offset.
+func linearPredictor(A *mat64.Dense, beta, offset []float64) []float64 {
+	n, _ := A.Dims()
+	eta := make([]float64, n)
+	for i := range eta {
+		eta[i] = dot(mat64.Row(nil, i, A), beta) + offset[i]
+	}
+	return eta
+}
+
+// glmDeviance returns the deviance at the linear predictor eta, and whether
+// eta gives a valid mean for every row.
+func glmDeviance(fam Family, y, prior, eta []float64) (float64, bool) {
+	dev := 0.0
+	for i, v := range eta {
+		mu := fam.Link.InverseFn(v)
+		if !(fam.VarianceFn(mu) > 0) || math.IsInf(mu, 0) {
+			return math.NaN(), false
 		}
+		dev += prior[i] * fam.deviance(y[i], mu)
+	}
+	return dev, true
+}
 
-		var (
-			wa     = matrixMult(wMat, A)
-			cprod1 = matrixMult(wa.T(), A)
-			wz     = matrixMult(wMat, z)
-			cprod2 = matrixMult(wz.T(), A)
-		)
+// GLM is a fitted generalized linear model.
+type GLM struct {
+	betas  []float64
+	family Family
+}
 
-		// save xold for evaluating convergence
-		xold := mat64.NewDense(ncol, 1, nil)
-		x.Clone(xold)
+// Predict returns the predicted mean of the response, invLink(x beta).
+func (g *GLM) Predict(x []float64) float64 {
+	return g.PredictOffset(x, 0)
+}
+
+// PredictOffset returns the predicted mean of the response for a row with the
+// given offset, invLink(x beta + offset).
+func (g *GLM) PredictOffset(x []float64, offset float64) float64 {
+	return g.family.Link.InverseFn(g.betas[0] + sum(prod(x, g.betas[1:])) + offset)
+}
 
-		// xnew = solve(crossprod(A,W*A), crossprod(A,W*z))
-		x = &mat64.Dense{}
-		err = x.Solve(cprod1, cprod2.T())
-		if err != nil {
-			return nil, nil, err
+// GLMSummary summarizes a fitted generalized linear model.
+type GLMSummary struct {
+	betas        []float64
+	stdErrs      []float64
+	zValues      []float64
+	pValues      []float64
+	fitted       []float64
+	residuals    []float64
+	response     []float64
+	nullDeviance float64
+	deviance     float64
+	aic          float64
+	dispersion   float64
+	theta        float64
+	iterations   int
+	n, p         int
+	data         *DataFrame
+}
+
+// newGLMSummary computes the fit statistics at the final coefficients. The
+// covariance of the coefficients is dispersion * (Xt W X)^-1, with the
+// dispersion estimated by the Pearson statistic unless the family fixes it.
+// Rows with zero prior weight do not count towards the degrees of freedom.
+func newGLMSummary(config *GLMConfig, data *DataFrame, y, prior, offset, betas []float64, iters int) (*GLMSummary, error) {
+	fam := config.F
+	n, p := data.Rows(), data.Cols()
+	A := data.Data()
+
+	var (
+		mu        = make([]float64, n)
+		w         = make([]float64, n)
+		residuals = make([]float64, n)
+		pearson   = 0.0
+		nobs      = 0
+	)
+	for i := 0; i < n; i++ {
+		eta := dot(mat64.Row(nil, i, A), betas) + offset[i]
+		mu[i] = fam.Link.InverseFn(eta)
+		gprime := fam.Link.DerivativeFn(eta)
+		v := fam.VarianceFn(mu[i])
+		w[i] = prior[i] * gprime * gprime / v
+		residuals[i] = y[i] - mu[i]
+		pearson += prior[i] * residuals[i] * residuals[i] / v
+		if prior[i] > 0 {
+			nobs++
 		}
+	}
 
-		// convergence = sqrt(crossprod(x - xold)) <= tolerance
-		diff := &mat64.Dense{}
-		diff.Sub(x, xold)
-		conv := matrixMult(diff.T(), diff)
-		if math.Sqrt(conv.At(0, 0)) <= l.config.Tolerance {
-			break
+	dispersion := fam.Dispersion
+	if dispersion <= 0 {
+		dispersion = pearson / float64(nobs-p)
+	}
+
+	// Xt W X, without forming W
+	xtwx := mat64.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		for k := j; k < p; k++ {
+			s := 0.0
+			for i := 0; i < n; i++ {
+				s += A.At(i, j) * w[i] * A.At(i, k)
+			}
+			xtwx.Set(j, k, s)
+			xtwx.Set(k, j, s)
 		}
 	}
+	cov := &mat64.Dense{}
+	if err := cov.Inverse(xtwx); err != nil && !illConditioned(err) {
+		return nil, err
+	}
 
-	coef := mat64.Col(nil, 0, x)
-	fmt.Printf("coef=%v x=%v", coef, x)
-	return nil, nil, nil
+	s := &GLMSummary{
+		betas:      betas,
+		stdErrs:    make([]float64, p),
+		zValues:    make([]float64, p),
+		pValues:    make([]float64, p),
+		fitted:     mu,
+		residuals:  residuals,
+		response:   y,
+		dispersion: dispersion,
+		theta:      math.NaN(),
+		iterations: iters,
+		n:          nobs,
+		p:          p,
+		data:       data,
+	}
+
+	// Wald tests: normal when the dispersion is known, t on n - p degrees
+	// of freedom when it is estimated
+	for j := range betas {
+		s.stdErrs[j] = math.Sqrt(dispersion * cov.At(j, j))
+		s.zValues[j] = betas[j] / s.stdErrs[j]
+		if fam.Dispersion > 0 {
+			s.pValues[j] = math.Erfc(math.Abs(s.zValues[j]) / math.Sqrt2)
+		} else {
+			s.pValues[j] = 1 - stat.F_CDF(1, float64(nobs-p))(s.zValues[j]*s.zValues[j])
+		}
+	}
+
+	nullMu, err := nullMeans(config, y, prior, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range y {
+		s.deviance += prior[i] * fam.deviance(y[i], mu[i])
+		s.nullDeviance += prior[i] * fam.deviance(y[i], nullMu[i])
+	}
+
+	if fam.theta > 0 {
+		s.theta = fam.theta
+	}
+
+	// AIC = -2 loglik + 2k, counting the dispersion or theta when they are
+	// estimated
+	s.aic = math.NaN()
+	if fam.LogLikFn != nil {
+		k := float64(p)
+		if fam.estimateTheta {
+			k++
+		}
+		phi := fam.Dispersion
+		if phi <= 0 {
+			phi = s.deviance / float64(nobs)
+			k++
+		}
+		ll := 0.0
+		for i := range y {
+			if prior[i] > 0 {
+				ll += fam.LogLikFn(y[i], mu[i], prior[i], phi)
+			}
+		}
+		s.aic = -2*ll + 2*k
+	}
+
+	return s, nil
 }
 
-func matrixMult(a, b mat64.Matrix) *mat64.Dense {
-	out := &mat64.Dense{}
-	out.Mul(a, b)
-	return out
+// nullMeans returns the fitted means of the intercept-only model. Without an
+// offset this is the weighted mean of y whatever the link; with one, the
+// intercept has to be fit.
+func nullMeans(config *GLMConfig, y, prior, offset []float64) ([]float64, error) {
+	n := len(y)
+	if sum(prod(offset, offset)) == 0 {
+		return rep(sum(prod(prior, y))/sum(prior), n), nil
+	}
+
+	ones := mat64.NewDense(n, 1, rep(1.0, n))
+	b0, _, err := irls(config, ones, y, prior, offset)
+	if err != nil {
+		return nil, err
+	}
+	mu := make([]float64, n)
+	for i := range mu {
+		mu[i] = config.F.Link.InverseFn(b0[0] + offset[i])
+	}
+	return mu, nil
 }
 
-type linkFunc func(float64) float64
+func (g *GLMSummary) Data() *DataFrame        { return g.data }
+func (g *GLMSummary) Coefficients() []float64 { return g.betas }
+func (g *GLMSummary) Yhat() []float64         { return g.fitted }
+func (g *GLMSummary) Response() []float64     { return g.response }
 
-type fnType uint8
+// Residuals returns the response residuals y - mu.
+func (g *GLMSummary) Residuals() []float64 { return g.residuals }
 
-const (
-	Link fnType = iota
-	Derivative
-	Variance
-)
+// SumOfSquares returns the sum of squared response residuals.
+func (g *GLMSummary) SumOfSquares() float64 {
+	return sum(prod(g.residuals, g.residuals))
+}
+
+// StdErrors returns the standard errors of the coefficients.
+func (g *GLMSummary) StdErrors() []float64 { return g.stdErrs }
+
+// ZValues returns the Wald statistics, coefficient / standard error.
+func (g *GLMSummary) ZValues() []float64 { return g.zValues }
+
+// PValues returns the two-sided p-values of the Wald statistics.
+func (g *GLMSummary) PValues() []float64 { return g.pValues }
+
+// NullDeviance returns the deviance of the intercept-only model.
+func (g *GLMSummary) NullDeviance() float64 { return g.nullDeviance }
+
+// Deviance returns the residual deviance.
+func (g *GLMSummary) Deviance() float64 { return g.deviance }
+
+// AIC returns the Akaike information criterion, or NaN if the family has no
+// likelihood.
+func (g *GLMSummary) AIC() float64 { return g.aic }
+
+// Dispersion returns the dispersion used for the standard errors: fixed at 1
+// for the binomial, Poisson and negative binomial families, and estimated by
+// the Pearson statistic over the residual degrees of freedom otherwise.
+func (g *GLMSummary) Dispersion() float64 { return g.dispersion }
+
+// Theta returns the negative binomial theta, or NaN for other families.
+func (g *GLMSummary) Theta() float64 { return g.theta }
+
+// Iterations returns the number of IRLS iterations run.
+func (g *GLMSummary) Iterations() int { return g.iterations }
+
+func (g *GLMSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+		z values:
+		%v
+		P-values:
+		%v
+
+		Null deviance: %v on %d degrees of freedom
+		Residual deviance: %v on %d degrees of freedom
+		AIC: %v
+		Dispersion: %v
+		Theta: %v
+		Iterations: %d`,
+		roundAll(g.betas),
+		roundAll(g.stdErrs),
+		roundAll(g.zValues),
+		g.pValues,
+		round(g.nullDeviance, 3), g.n-1,
+		round(g.deviance, 3), g.n-g.p,
+		round(g.aic, 3),
+		round(g.dispersion, 4),
+		round(g.theta, 4),
+		g.iterations,
+	)
+}
 
 type evalFn func(float64) float64
 
+// unit deviance d(y, mu); the deviance is the sum over the observations
+type devianceFn func(y, mu float64) float64
+
+// log-likelihood of a single observation with mean mu, prior weight w and
+// dispersion phi
+type logLikFn func(y, mu, w, phi float64) float64
+
+// starting value of mu for a response y with prior weight w
+type initFn func(y, w float64) float64
+
+// validFn reports whether a response is in the support of a family.
+type validFn func(y float64) bool
+
+// A Family describes the distribution of the response through its variance
+// function, and is fit with the given Link. Use WithLink to fit a family with
+// a link other than its canonical one.
 type Family struct {
-	LinkFn       evalFn
-	VarianceFn   evalFn
-	DerivativeFn evalFn
+	Name       string
+	Link       Link
+	VarianceFn evalFn
+	DevianceFn devianceFn // optional; used for the deviance
+	LogLikFn   logLikFn   // optional; used for the AIC
+	InitFn     initFn     // optional; starting value of mu for a response
+	Dispersion float64    // fixed dispersion, or 0 if it is estimated
+	links      []string   // names of the links the family accepts; nil for any
+	support    validFn    // whether the family accepts a response; nil for any
+
+	theta         float64 // negative binomial theta; 0 for other families
+	estimateTheta bool    // theta is estimated along with the coefficients
 }
 
-func NewFamily(l, d, v evalFn) Family {
+// NewFamily returns a family with the given link and variance function. It
+// accepts any link.
+func NewFamily(name string, link Link, v evalFn) Family {
 	return Family{
-		LinkFn:       l,
-		VarianceFn:   v,
-		DerivativeFn: d,
+		Name:       name,
+		Link:       link,
+		VarianceFn: v,
+	}
+}
+
+// WithLink returns a copy of the family using the given link, or an error if
+// the link is not one the family accepts.
+func (f Family) WithLink(link Link) (Family, error) {
+	if f.links != nil && !containsString(link.Name, f.links) {
+		return Family{}, fmt.Errorf("link %q is not valid for the %s family", link.Name, f.Name)
 	}
+	f.Link = link
+	return f, nil
+}
+
+// deviance falls back on the squared Pearson residual for families without
+// a deviance function.
+func (f Family) deviance(y, mu float64) float64 {
+	if f.DevianceFn == nil {
+		return (y - mu) * (y - mu) / f.VarianceFn(mu)
+	}
+	return f.DevianceFn(y, mu)
+}
+
+// Accepts reports whether y is a valid response for the family: a proportion
+// in [0, 1] for the binomial families, and anything for the others.
+func (f Family) Accepts(y float64) bool {
+	return f.support == nil || f.support(y)
+}
+
+// initial returns the starting value of mu for the response y with prior
+// weight w.
+func (f Family) initial(y, w float64) float64 {
+	if f.InitFn == nil {
+		return y
+	}
+	return f.InitFn(y, w)
 }
 
 var (
-	Binomial  = NewFamily(binomialLink, binomialDerivative, binomialVariance)
-	Poisson   = NewFamily(poissonLink, poissonDerivative, poissonVariance)
-	Gamma     = NewFamily(gammaLink, gammaDerivative, gammaVariance)
-	InvNormal = NewFamily(invnLink, invnDerivative, invnVariance)
+	Gaussian = Family{
+		Name:       "gaussian",
+		Link:       Identity,
+		VarianceFn: one,
+		DevianceFn: gaussianDeviance,
+		LogLikFn:   gaussianLogLik,
+		links:      []string{"identity", "log", "inverse"},
+	}
+	Binomial = Family{
+		Name:       "binomial",
+		Link:       Logit,
+		VarianceFn: binomialVariance,
+		DevianceFn: binomialDeviance,
+		LogLikFn:   binomialLogLik,
+		InitFn:     binomialInit,
+		Dispersion: 1,
+		links:      []string{"logit", "probit", "cloglog", "log"},
+		support:    isProportion,
+	}
+	Poisson = Family{
+		Name:       "poisson",
+		Link:       Log,
+		VarianceFn: poissonVariance,
+		DevianceFn: poissonDeviance,
+		LogLikFn:   poissonLogLik,
+		InitFn:     poissonInit,
+		Dispersion: 1,
+		links:      []string{"log", "identity", "sqrt"},
+	}
+	Gamma = Family{
+		Name:       "gamma",
+		Link:       Inverse,
+		VarianceFn: gammaVariance,
+		DevianceFn: gammaDeviance,
+		LogLikFn:   gammaLogLik,
+		links:      []string{"inverse", "identity", "log"},
+	}
+	// QuasiPoisson has the Poisson variance, mu, scaled by an estimated
+	// dispersion. There is no likelihood, so no AIC.
+	QuasiPoisson = Family{
+		Name:       "quasipoisson",
+		Link:       Log,
+		VarianceFn: poissonVariance,
+		DevianceFn: poissonDeviance,
+		InitFn:     poissonInit,
+		links:      []string{"log", "identity", "sqrt"},
+	}
+	// QuasiBinomial has the binomial variance, mu (1 - mu), scaled by an
+	// estimated dispersion. There is no likelihood, so no AIC.
+	QuasiBinomial = Family{
+		Name:       "quasibinomial",
+		Link:       Logit,
+		VarianceFn: binomialVariance,
+		DevianceFn: binomialDeviance,
+		InitFn:     binomialInit,
+		links:      []string{"logit", "probit", "cloglog", "log"},
+		support:    isProportion,
+	}
+	InvNormal = Family{
+		Name:       "inverse gaussian",
+		Link:       InverseSquared,
+		VarianceFn: invnVariance,
+		DevianceFn: invnDeviance,
+		LogLikFn:   invnLogLik,
+		links:      []string{"1/mu^2", "inverse", "identity", "log"},
+	}
 )
 
+// y log(y / mu), taken as 0 when y = 0
+func ylogy(y, mu float64) float64 {
+	if y == 0 {
+		return 0
+	}
+	return y * math.Log(y/mu)
+}
+
 // -------------------------- //
-//          Binomial
+//          Gaussian
 // -------------------------- //
 
-// logistic link function
-func binomialLink(x float64) float64 {
-	return 1 / (1 + math.Exp(-x))
+func gaussianDeviance(y, mu float64) float64 {
+	return (y - mu) * (y - mu)
 }
 
-// derivative of logistic f(x) = f(x) * (1 - f(x))
-func binomialDerivative(x float64) float64 {
-	l := 1 / (1 + math.Exp(-x))
-	ans := l * (1 - l)
-	return ans
+// normal with variance phi / w
+func gaussianLogLik(y, mu, w, phi float64) float64 {
+	return -0.5 * (math.Log(2*math.Pi*phi/w) + w*(y-mu)*(y-mu)/phi)
 }
 
+// -------------------------- //
+//          Binomial
+// -------------------------- //
+
 // mean = x 	variance = np(1 - p) = p - p^2
 func binomialVariance(x float64) float64 {
 	return x - math.Pow(x, 2.0)
 }
 
+// 2 (y log(y/mu) + (1 - y) log((1 - y)/(1 - mu)))
+func binomialDeviance(y, mu float64) float64 {
+	return 2 * (ylogy(y, mu) + ylogy(1-y, 1-mu))
+}
+
+// w y successes out of w trials:
+// log(choose(w, w y)) + w (y log(mu) + (1 - y) log(1 - mu))
+func binomialLogLik(y, mu, w, _ float64) float64 {
+	m, k := math.Round(w), math.Round(w*y)
+	lm, _ := math.Lgamma(m + 1)
+	lk, _ := math.Lgamma(k + 1)
+	lmk, _ := math.Lgamma(m - k + 1)
+	ll := lm - lk - lmk
+	if k > 0 {
+		ll += k * math.Log(mu)
+	}
+	if m > k {
+		ll += (m - k) * math.Log(1-mu)
+	}
+	return ll
+}
+
+// the binomial response is a proportion of successes
+func isProportion(y float64) bool { return y >= 0 && y <= 1 }
+
+// start between the observed proportion and 1/2, so mu is inside (0, 1) for
+// every link
+func binomialInit(y, w float64) float64 {
+	return (w*math.Min(math.Max(y, 0), 1) + 0.5) / (w + 1)
+}
+
 // -------------------------- //
 //          Poisson
 // -------------------------- //
 
-// exponential link function
-func poissonLink(x float64) float64 {
-	return math.Exp(x)
+func poissonVariance(x float64) float64 {
+	return x
 }
 
-func poissonDerivative(x float64) float64 {
-	return poissonLink(x)
+// 2 (y log(y/mu) - (y - mu))
+func poissonDeviance(y, mu float64) float64 {
+	return 2 * (ylogy(y, mu) - (y - mu))
 }
 
-func poissonVariance(x float64) float64 {
-	return x
+// weights count repeated observations
+func poissonLogLik(y, mu, w, _ float64) float64 {
+	lg, _ := math.Lgamma(y + 1)
+	return w * (y*math.Log(mu) - mu - lg)
+}
+
+// keep zero counts off the boundary of the log link
+func poissonInit(y, _ float64) float64 {
+	return y + 0.1
 }
 
 // -------------------------- //
 //          Gamma
 // -------------------------- //
 
-// inverse link function: 1/x
-func gammaLink(x float64) float64 {
-	return 1 / x
+// variance of gamma dist: kx^2, but k=1
+func gammaVariance(x float64) float64 {
+	return math.Pow(x, 2.0)
 }
 
-// derivative of link: 1/x^2
-func gammaDerivative(x float64) float64 {
-	return 1 / math.Pow(x, 2.0)
+// 2 (-log(y/mu) + (y - mu)/mu)
+func gammaDeviance(y, mu float64) float64 {
+	return 2 * (-math.Log(y/mu) + (y-mu)/mu)
 }
 
-// variance of gamma dist: kx^2, but k=1
-func gammaVariance(x float64) float64 {
-	return math.Pow(x, 2.0)
+// gamma with shape 1/phi and mean mu, weights counting repeated observations
+func gammaLogLik(y, mu, w, phi float64) float64 {
+	shape := 1 / phi
+	lg, _ := math.Lgamma(shape)
+	return w * (shape*math.Log(shape*y/mu) - shape*y/mu - math.Log(y) - lg)
 }
 
 // -------------------------- //
 //       Inverse Normal
 // -------------------------- //
 
-// Link function: 1 / sqrt(x)
-
-func invnLink(x float64) float64 { return 1 / math.Sqrt(x) }
-
-// Derivative of Link: - (x ^ 3/2) / 2
-
-func invnDerivative(x float64) float64 {
-	return -0.5 * math.Pow(x, -1.5)
-}
-
 // Variance : mu^3 / lambda , lambda = 1
 func invnVariance(x float64) float64 {
 	return math.Pow(x, 3.0)
 }
+
+// (y - mu)^2 / (y mu^2)
+func invnDeviance(y, mu float64) float64 {
+	return (y - mu) * (y - mu) / (y * mu * mu)
+}
+
+// weights count repeated observations
+func invnLogLik(y, mu, w, phi float64) float64 {
+	return -0.5 * w * (math.Log(2*math.Pi*phi*y*y*y) + invnDeviance(y, mu)/phi)
+}
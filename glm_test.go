@@ -1,6 +1,8 @@
 package glasso
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -16,3 +18,156 @@ func TestGLM(t *testing.T) {
 	_, _, err := glm.Train(df, y)
 	assert.Equal(t, nil, err)
 }
+
+// Dobson (1990), p. 93: counts by outcome and treatment, as in the example
+// for R's glm
+func TestGLMPoisson(t *testing.T) {
+	var (
+		counts = []float64{18, 17, 15, 20, 10, 20, 25, 13, 12}
+		dobson = [][]float64{ // outcome2, outcome3, treatment2, treatment3
+			{0, 0, 0, 0},
+			{1, 0, 0, 0},
+			{0, 1, 0, 0},
+			{0, 0, 1, 0},
+			{1, 0, 1, 0},
+			{0, 1, 1, 0},
+			{0, 0, 0, 1},
+			{1, 0, 0, 1},
+			{0, 1, 0, 1},
+		}
+		config = NewGLMConfig(Poisson, 25, 1e-8)
+	)
+
+	model, summary, err := NewGlmTrainer(config).Train(NewDataFrame(dobson), counts)
+	assert.Equal(t, nil, err)
+
+	s := summary.(*GLMSummary)
+	assertNear(t, []float64{3.0445, -0.4543, -0.2930, 0, 0}, s.Coefficients(), 1e-4)
+	assertNear(t, []float64{0.1709, 0.2022, 0.1927, 0.2000, 0.2000}, s.StdErrors(), 1e-4)
+	assert.Equal(t, 5.129, round(s.Deviance(), 3))
+	assert.Equal(t, 10.58, round(s.NullDeviance(), 2))
+	assert.Equal(t, 56.76, round(s.AIC(), 2))
+	assert.Equal(t, 1.0, s.Dispersion())
+
+	// the model predicts the mean count
+	assert.Equal(t, 21.0, round(model.Predict([]float64{0, 0, 0, 0}), 1))
+}
+
+// with a single binary column the fitted means are the group means whatever
+// the link, so the coefficients are the link applied to them
+func TestGLMLinks(t *testing.T) {
+	group := NewDataFrame([][]float64{{0}, {0}, {0}, {0}, {1}, {1}, {1}, {1}})
+
+	probit, err := Binomial.WithLink(Probit)
+	assert.Equal(t, nil, err)
+	_, summary, err := NewGlmTrainer(NewGLMConfig(probit, 25, 1e-10)).Train(group, []float64{0, 1, 0, 0, 1, 1, 0, 1})
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{probitLink(0.25), probitLink(0.75) - probitLink(0.25)}, summary.Coefficients(), 1e-6)
+
+	gamma, err := Gamma.WithLink(Log)
+	assert.Equal(t, nil, err)
+	model, summary, err := NewGlmTrainer(NewGLMConfig(gamma, 25, 1e-10)).Train(group, []float64{1, 2, 3, 2, 4, 5, 6, 5})
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{math.Log(2), math.Log(5.0 / 2)}, summary.Coefficients(), 1e-6)
+	assert.Equal(t, 5.0, round(model.Predict([]float64{1}), 6))
+
+	_, err = Poisson.WithLink(Logit)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestGLMWeightsOffset(t *testing.T) {
+	// 3/4 and 1/4 successes as proportions with weights, and as single trials
+	grouped := NewDataFrame([][]float64{{0}, {1}})
+	config := NewGLMConfig(Binomial, 25, 1e-10)
+	config.Weights = []float64{4, 4}
+	_, summary, err := NewGlmTrainer(config).Train(grouped, []float64{0.75, 0.25})
+	assert.Equal(t, nil, err)
+
+	single := NewDataFrame([][]float64{{0}, {0}, {0}, {0}, {1}, {1}, {1}, {1}})
+	_, expanded, err := NewGlmTrainer(NewGLMConfig(Binomial, 25, 1e-10)).Train(single, []float64{1, 1, 1, 0, 1, 0, 0, 0})
+	assert.Equal(t, nil, err)
+	assertNear(t, expanded.Coefficients(), summary.Coefficients(), 1e-6)
+	assertNear(t, expanded.(*GLMSummary).StdErrors(), summary.(*GLMSummary).StdErrors(), 1e-6)
+
+	// counts over exposure: the fitted rate of each group is its total count
+	// over its total exposure
+	exposure := []float64{1, 2, 3, 2, 4, 2}
+	config = NewGLMConfig(Poisson, 25, 1e-10)
+	config.Offset = make([]float64, len(exposure))
+	for i, e := range exposure {
+		config.Offset[i] = math.Log(e)
+	}
+	group := NewDataFrame([][]float64{{0}, {0}, {0}, {1}, {1}, {1}})
+	model, summary, err := NewGlmTrainer(config).Train(group, []float64{2, 3, 7, 5, 9, 6})
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{math.Log(2), math.Log(2.5 / 2)}, summary.Coefficients(), 1e-6)
+	assert.Equal(t, 2.0, round(model.Predict([]float64{0}), 6))
+	assert.Equal(t, 7.5, round(model.(*GLM).PredictOffset([]float64{1}, math.Log(3)), 6))
+
+	// the null model fits a common rate of 32 / 14
+	null := 0.0
+	for i, c := range []float64{2, 3, 7, 5, 9, 6} {
+		null += poissonDeviance(c, exposure[i]*32/14)
+	}
+	assert.Equal(t, round(null, 6), round(summary.(*GLMSummary).NullDeviance(), 6))
+
+	config.Offset = []float64{1}
+	_, _, err = NewGlmTrainer(config).Train(group, []float64{2, 3, 7, 5, 9, 6})
+	assert.Equal(t, DimensionError, err)
+}
+
+// with trials as prior weights, a binomial response is a proportion
+func TestGLMResponses(t *testing.T) {
+	assert.Equal(t, true, Binomial.Accepts(0.25))
+	assert.Equal(t, false, Binomial.Accepts(3))
+	assert.Equal(t, false, QuasiBinomial.Accepts(-0.5))
+	assert.Equal(t, true, Poisson.Accepts(3))
+
+	grouped := NewDataFrame([][]float64{{0}, {1}})
+	config := NewGLMConfig(Binomial, 25, 1e-10)
+	config.Weights = []float64{4, 4}
+	_, _, err := NewGlmTrainer(config).Train(grouped, []float64{3, 1})
+	assert.NotEqual(t, nil, err)
+}
+
+// large enough that an n x n weight matrix would not fit in memory
+func TestGLMLarge(t *testing.T) {
+	var (
+		n    = 60000
+		r    = rand.New(rand.NewSource(1))
+		rows = make([][]float64, n)
+		resp = make([]float64, n)
+	)
+	for i := range rows {
+		rows[i] = []float64{r.NormFloat64(), r.NormFloat64()}
+		p := logitInverse(-0.5 + rows[i][0] - 2*rows[i][1])
+		if r.Float64() < p {
+			resp[i] = 1
+		}
+	}
+
+	_, summary, err := NewGlmTrainer(NewGLMConfig(Binomial, 25, 1e-8)).Train(NewDataFrame(rows), resp)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{-0.5, 1, -2}, summary.Coefficients(), 0.05)
+}
+
+// the first full step of an identity-link Poisson fit gives a negative mean,
+// so it is halved
+func TestGLMStepHalving(t *testing.T) {
+	var (
+		ident, _ = Poisson.WithLink(Identity)
+		df       = NewDataFrame([][]float64{{0}, {1}, {2}, {3}, {4}, {5}, {6}})
+		counts   = []float64{1, 2, 1, 3, 5, 10, 16}
+	)
+	_, summary, err := NewGlmTrainer(NewGLMConfig(ident, 200, 1e-10)).Train(df, counts)
+	assert.Equal(t, nil, err)
+
+	// score equations: sum (y / mu - 1) x = 0
+	mu := summary.Yhat()
+	score := []float64{0, 0}
+	for i, c := range counts {
+		score[0] += c/mu[i] - 1
+		score[1] += (c/mu[i] - 1) * float64(i)
+	}
+	assertNear(t, []float64{0, 0}, score, 1e-6)
+}
@@ -0,0 +1,327 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Generalized Least Squares regression for errors with covariance σ^2 Σ,
+// such as autocorrelated errors. With the Cholesky factor Σ = LLt, the
+// whitened model
+//
+// L^-1 y = L^-1 X β + L^-1 ε
+//
+// has uncorrelated errors, so it is fit by ordinary least squares:
+// β = (Xt Σ^-1 X)^-1 Xt Σ^-1 y, with var(β) = σ^2 (Xt Σ^-1 X)^-1.
+//
+// For AR(1) errors, ε_t = ρ ε_t-1 + u_t, whitening amounts to the
+// quasi-differences y_t - ρ y_t-1 and x_t - ρ x_t-1. ρ is unknown, so it is
+// estimated from the residuals and the fit repeated until ρ settles.
+type GLS struct {
+	betas []float64
+}
+
+func (g *GLS) Predict(x []float64) float64 {
+	return g.betas[0] + sum(prod(x, g.betas[1:]))
+}
+
+// AR1Method is the treatment of the first observation in an AR(1) fit.
+type AR1Method uint8
+
+const (
+	CochraneOrcutt AR1Method = iota // drops the first observation
+	PraisWinsten                    // keeps it, scaled by sqrt(1 - ρ^2)
+)
+
+type glsTrainer struct {
+	sigma  *DataFrame
+	method AR1Method
+	maxIt  int
+}
+
+// NewGLSTrainer returns a Trainer for errors with covariance proportional to
+// the n x n positive definite matrix sigma.
+func NewGLSTrainer(sigma *DataFrame) Trainer {
+	return &glsTrainer{
+		sigma: sigma,
+	}
+}
+
+// NewAR1Trainer returns a Trainer for AR(1) errors, iterating the estimate of
+// ρ at most maxIt times (50 if not positive).
+func NewAR1Trainer(method AR1Method, maxIt int) Trainer {
+	if maxIt <= 0 {
+		maxIt = 50
+	}
+	return &glsTrainer{
+		method: method,
+		maxIt:  maxIt,
+	}
+}
+
+// GlsSummary is the summary of a generalized least squares fit. Its Summary
+// methods are on the original scale of y, like those of every other fit; the
+// whitened regression, whose errors are uncorrelated, is given by Whitened,
+// so that VarCov and the other diagnostics apply to it directly.
+type GlsSummary struct {
+	betas      []float64
+	residuals  []float64
+	fitted     []float64
+	response   []float64
+	whitened   OlsSummary
+	stdErrs    []float64
+	rho        float64
+	sigma      float64
+	iterations int
+	data       *DataFrame
+}
+
+func (g GlsSummary) Data() *DataFrame        { return g.data }
+func (g GlsSummary) Coefficients() []float64 { return g.betas }
+func (g GlsSummary) Residuals() []float64    { return g.residuals }
+func (g GlsSummary) Yhat() []float64         { return g.fitted }
+func (g GlsSummary) Response() []float64     { return g.response }
+
+func (g GlsSummary) SumOfSquares() float64 {
+	return sum(prod(g.residuals, g.residuals))
+}
+
+// Whitened returns the summary of the whitened regression L^-1 y on L^-1 X.
+// Under Cochrane-Orcutt it has n - 1 rows.
+func (g GlsSummary) Whitened() Summary { return g.whitened }
+
+// WhitenedResiduals returns the residuals of the whitened regression.
+func (g GlsSummary) WhitenedResiduals() []float64 { return g.whitened.residuals }
+
+// StdErrors returns the standard errors of the coefficients.
+func (g GlsSummary) StdErrors() []float64 { return g.stdErrs }
+
+// Rho returns the estimated AR(1) coefficient, or NaN for a given covariance.
+func (g GlsSummary) Rho() float64 { return g.rho }
+
+// Sigma returns the residual standard error of the whitened regression.
+func (g GlsSummary) Sigma() float64 { return g.sigma }
+
+// Iterations returns the number of times ρ was estimated.
+func (g GlsSummary) Iterations() int { return g.iterations }
+
+func (g GlsSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+
+		Rho: %v
+		Residual standard error: %v`,
+		roundAll(g.betas),
+		roundAll(g.stdErrs),
+		round(g.rho, 4),
+		round(g.sigma, 4),
+	)
+}
+
+func (g *glsTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	data := withIntercept(x)
+
+	if g.sigma != nil {
+		if g.sigma.Rows() != n || g.sigma.Cols() != n {
+			return nil, nil, DimensionError
+		}
+		l, err := cholesky(g.sigma.Data())
+		if err != nil {
+			return nil, nil, err
+		}
+		xw := mat64.NewDense(n, data.Cols(), nil)
+		for j := 0; j < data.Cols(); j++ {
+			xw.SetCol(j, forwardSolve(l, data.GetCol(j)))
+		}
+		return glsFit(data, xw, y, forwardSolve(l, y), math.NaN(), 0)
+	}
+
+	if n < 2 {
+		return nil, nil, DimensionError
+	}
+
+	// start from OLS, then alternate between ρ and β
+	betas, err := leastSquares(data.Data(), y)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		rho   float64
+		xw    *mat64.Dense
+		yw    []float64
+		iters int
+	)
+	for iters < g.maxIt {
+		iters++
+		_, e := linearFit(x, betas, y)
+		rhoNew := dot(e[1:], e[:n-1]) / dot(e[:n-1], e[:n-1])
+		if math.Abs(rhoNew) >= 1 {
+			return nil, nil, fmt.Errorf("estimated rho %v is not stationary", rhoNew)
+		}
+
+		xw, yw = quasiDifference(data, y, rhoNew, g.method)
+		betas, err = leastSquares(xw, yw)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		converged := math.Abs(rhoNew-rho) <= DefaultTolerance
+		rho = rhoNew
+		if converged {
+			break
+		}
+	}
+
+	return glsFit(data, xw, y, yw, rho, iters)
+}
+
+// quasiDifference returns the AR(1) whitened design and response, z_t -
+// ρ z_t-1, with the first row dropped (Cochrane-Orcutt) or scaled by
+// sqrt(1 - ρ^2) (Prais-Winsten).
+func quasiDifference(data *DataFrame, y []float64, rho float64, method AR1Method) (*mat64.Dense, []float64) {
+	n, p := data.Rows(), data.Cols()
+	x := data.Data()
+
+	first := 1
+	if method == PraisWinsten {
+		first = 0
+	}
+	xw := mat64.NewDense(n-first, p, nil)
+	yw := make([]float64, n-first)
+	for t := first; t < n; t++ {
+		for j := 0; j < p; j++ {
+			if t == 0 {
+				xw.Set(0, j, math.Sqrt(1-rho*rho)*x.At(0, j))
+			} else {
+				xw.Set(t-first, j, x.At(t, j)-rho*x.At(t-1, j))
+			}
+		}
+		if t == 0 {
+			yw[0] = math.Sqrt(1-rho*rho) * y[0]
+		} else {
+			yw[t-first] = y[t] - rho*y[t-1]
+		}
+	}
+	return xw, yw
+}
+
+// glsFit fits the whitened regression and builds the model and summary.
+func glsFit(data *DataFrame, xw *mat64.Dense, y, yw []float64, rho float64, iters int) (Model, Summary, error) {
+	betas, err := leastSquares(xw, yw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the whitened intercept column is not constant, so the fit uses the
+	// whole whitened design
+	wFitted := make([]float64, len(yw))
+	wResiduals := make([]float64, len(yw))
+	for i := range wFitted {
+		wFitted[i] = dot(mat64.Row(nil, i, xw), betas)
+		wResiduals[i] = yw[i] - wFitted[i]
+	}
+
+	// var(β) = s^2 (Xt Σ^-1 X)^-1
+	nw, p := xw.Dims()
+	xtx := &mat64.Dense{}
+	xtx.Mul(xw.T(), xw)
+	cov := &mat64.Dense{}
+	if err := cov.Inverse(xtx); err != nil {
+		return nil, nil, err
+	}
+	s2 := dot(wResiduals, wResiduals) / float64(nw-p)
+	stdErrs := make([]float64, p)
+	for j := range stdErrs {
+		stdErrs[j] = math.Sqrt(s2 * cov.At(j, j))
+	}
+
+	// the fit on the original scale, with the intercept column of data
+	fitted := make([]float64, len(y))
+	residuals := make([]float64, len(y))
+	for i := range y {
+		fitted[i] = dot(data.GetRow(i), betas)
+		residuals[i] = y[i] - fitted[i]
+	}
+	response := make([]float64, len(y))
+	copy(response, y)
+
+	return &GLS{
+		betas: betas,
+	}, GlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		whitened: OlsSummary{
+			betas:     betas,
+			residuals: wResiduals,
+			fitted:    wFitted,
+			response:  yw,
+			n:         nw,
+			p:         p - 1,
+			data:      Mat64ToDF(xw),
+		},
+		stdErrs:    stdErrs,
+		rho:        rho,
+		sigma:      math.Sqrt(s2),
+		iterations: iters,
+		data:       data,
+	}, nil
+}
+
+// leastSquares solves min ||X β - y|| by QR.
+func leastSquares(x *mat64.Dense, y []float64) ([]float64, error) {
+	qr := &mat64.QR{}
+	qr.Factorize(x)
+	b := &mat64.Dense{}
+	if err := b.SolveQR(qr, false, mat64.NewDense(len(y), 1, y)); err != nil {
+		return nil, err
+	}
+	return mat64.Col(nil, 0, b), nil
+}
+
+// cholesky returns the lower triangular L with a = LLt, as rows.
+func cholesky(a *mat64.Dense) ([][]float64, error) {
+	n, _ := a.Dims()
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, i+1)
+		for j := 0; j <= i; j++ {
+			s := a.At(i, j)
+			for k := 0; k < j; k++ {
+				s -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if s <= 0 {
+					return nil, fmt.Errorf("covariance matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(s)
+			} else {
+				l[i][j] = s / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// forwardSolve returns L^-1 v for lower triangular L.
+func forwardSolve(l [][]float64, v []float64) []float64 {
+	z := make([]float64, len(v))
+	for i := range v {
+		s := v[i]
+		for k := 0; k < i; k++ {
+			s -= l[i][k] * z[k]
+		}
+		z[i] = s / l[i][i]
+	}
+	return z
+}
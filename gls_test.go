@@ -0,0 +1,91 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+// a diagonal covariance is weighted least squares with weights 1 / sigma_ii
+func TestGLSDiagonal(t *testing.T) {
+	n := len(wlsY)
+	sigma := mat64.NewDense(n, n, nil)
+	for i, w := range wlsW {
+		sigma.Set(i, i, 1/w)
+	}
+
+	_, gls, err := NewGLSTrainer(Mat64ToDF(sigma)).Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+	_, wls, err := NewWlsTrainer(wlsW).Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+
+	assertNear(t, wls.Coefficients(), gls.Coefficients(), 1e-10)
+	assertNear(t, wls.Residuals(), gls.Residuals(), 1e-10)
+	assertNear(t, wls.Yhat(), gls.Yhat(), 1e-10)
+
+	// the diagnostics of the whitened regression give the GLS errors
+	vc, err := VarCov(gls.(GlsSummary).Whitened())
+	assert.Equal(t, nil, err)
+	se := gls.(GlsSummary).StdErrors()
+	assertNear(t, []float64{se[0] * se[0], se[1] * se[1]}, []float64{vc.GetRow(0)[0], vc.GetRow(1)[1]}, 1e-10)
+}
+
+func TestGLSAR1(t *testing.T) {
+	var (
+		n    = 400
+		r    = rand.New(rand.NewSource(7))
+		rows = make([][]float64, n)
+		y    = make([]float64, n)
+		e    = 0.0
+	)
+	for i := range rows {
+		rows[i] = []float64{float64(i) / 40, r.NormFloat64()}
+		e = 0.7*e + r.NormFloat64()
+		y[i] = 1 + 2*rows[i][0] - rows[i][1] + e
+	}
+	df := NewDataFrame(rows)
+
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+
+	for _, method := range []AR1Method{CochraneOrcutt, PraisWinsten} {
+		_, summary, err := NewAR1Trainer(method, 0).Train(df, y)
+		assert.Equal(t, nil, err)
+		s := summary.(GlsSummary)
+		assert.Equal(t, true, math.Abs(s.Rho()-0.7) < 0.1)
+		assertNear(t, []float64{1, 2, -1}, s.Coefficients(), 0.5)
+
+		// the whitened residuals are no longer autocorrelated
+		assert.Equal(t, true, DW(ols) < 1)
+		assert.Equal(t, true, math.Abs(DW(s.Whitened())-2) < 0.3)
+
+		// the summary is on the original scale
+		assert.Equal(t, n, len(s.Residuals()))
+		assertNear(t, s.Residuals(), diff(y, s.Yhat()), 1e-12)
+		assert.Equal(t, n, s.Data().Rows())
+	}
+
+	_, co, _ := NewAR1Trainer(CochraneOrcutt, 0).Train(df, y)
+	assert.Equal(t, n-1, co.(GlsSummary).Whitened().Data().Rows())
+	assert.Equal(t, n-1, len(co.(GlsSummary).WhitenedResiduals()))
+
+	_, _, err = NewAR1Trainer(CochraneOrcutt, 0).Train(NewDataFrame(rows[:1]), y[:1])
+	assert.Equal(t, DimensionError, err)
+
+	// Prais-Winsten is GLS with the AR(1) covariance rho^|i-j|
+	_, pw, _ := NewAR1Trainer(PraisWinsten, 0).Train(df, y)
+	rho := pw.(GlsSummary).Rho()
+	sigma := mat64.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			sigma.Set(i, j, math.Pow(rho, math.Abs(float64(i-j))))
+		}
+	}
+	_, full, err := NewGLSTrainer(Mat64ToDF(sigma)).Train(df, y)
+	assert.Equal(t, nil, err)
+	assertNear(t, full.Coefficients(), pw.Coefficients(), 1e-8)
+	assertNear(t, full.(GlsSummary).StdErrors(), pw.(GlsSummary).StdErrors(), 1e-8)
+}
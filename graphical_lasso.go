@@ -0,0 +1,372 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// The graphical lasso estimates a sparse precision matrix Θ = Σ^-1 for
+// multivariate normal data by maximizing the penalized log-likelihood
+//
+// log det Θ - tr(S Θ) - ρ ||Θ||_1
+//
+// where S is the sample covariance. A zero in Θ means the two variables are
+// independent given all the others, so the nonzero pattern is a graph of
+// conditional dependence.
+//
+// It is fit by block coordinate descent (Friedman, Hastie and Tibshirani,
+// 2008) on W = Θ^-1, starting from W = S + ρI. Each column in turn, with W
+// partitioned into the other variables (1) and the column (2), solves the
+// lasso problem
+//
+// min 1/2 β' W11 β - β' s12 + ρ ||β||_1
+//
+// by coordinate descent and sets w12 = W11 β, until W settles. Then
+// θ22 = 1 / (w22 - w12' β) and θ12 = -β θ22.
+//
+// Variables i and j can only be connected if they are linked by a chain of
+// pairs with |S_kl| > ρ, so the problem is solved separately on each connected
+// component of that graph (Witten, Friedman and Simon, 2011).
+type GraphicalModel struct {
+	precision  [][]float64
+	covariance [][]float64
+	sample     [][]float64
+	labels     []string
+	rho        float64
+	n          int
+	iterations int
+}
+
+// An Edge joins two variables that are dependent given all the others.
+type Edge struct {
+	I, J   int    // column indices, I < J
+	From   string // label of column I, if the data is labelled
+	To     string // label of column J, if the data is labelled
+	Weight float64
+}
+
+// GraphicalLasso estimates the sparse precision matrix of the columns of df
+// with penalty rho >= 0. The covariance is the maximum likelihood estimate,
+// with divisor n.
+func GraphicalLasso(df *DataFrame, rho float64) (*GraphicalModel, error) {
+	s, err := sampleCovariance(df)
+	if err != nil {
+		return nil, err
+	}
+	w, b := glassoInit(s, rho)
+	return glassoModel(df, s, rho, w, b)
+}
+
+// glassoModel fits the graphical lasso from the starting point w, b and
+// builds the model.
+func glassoModel(df *DataFrame, s [][]float64, rho float64, w, b [][]float64) (*GraphicalModel, error) {
+	if rho < 0 {
+		return nil, fmt.Errorf("rho must be non-negative")
+	}
+	theta, iters, err := glassoFit(s, rho, w, b, DefaultMaxIterations, DefaultTolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	covariance := make([][]float64, len(w))
+	for i := range w {
+		covariance[i] = append([]float64(nil), w[i]...)
+	}
+	return &GraphicalModel{
+		precision:  theta,
+		covariance: covariance,
+		sample:     s,
+		labels:     df.Labels(),
+		rho:        rho,
+		n:          df.Rows(),
+		iterations: iters,
+	}, nil
+}
+
+// Precision returns the estimated precision matrix Θ, labelled as the data.
+func (g *GraphicalModel) Precision() *DataFrame {
+	return NewDataFrame(g.precision, g.labels)
+}
+
+// Covariance returns the estimated covariance matrix W = Θ^-1, labelled as the
+// data.
+func (g *GraphicalModel) Covariance() *DataFrame {
+	return NewDataFrame(g.covariance, g.labels)
+}
+
+// Rho returns the penalty.
+func (g *GraphicalModel) Rho() float64 { return g.rho }
+
+// Iterations returns the number of sweeps over the columns.
+func (g *GraphicalModel) Iterations() int { return g.iterations }
+
+// Adjacency returns the adjacency matrix of the graph: whether Θ_ij != 0 for
+// i != j.
+func (g *GraphicalModel) Adjacency() [][]bool {
+	p := len(g.precision)
+	adj := make([][]bool, p)
+	for i := range adj {
+		adj[i] = make([]bool, p)
+		for j := range adj[i] {
+			adj[i][j] = i != j && g.precision[i][j] != 0
+		}
+	}
+	return adj
+}
+
+// Edges returns the edges of the graph, weighted by the partial correlation
+// -Θ_ij / sqrt(Θ_ii Θ_jj).
+func (g *GraphicalModel) Edges() []Edge {
+	var edges []Edge
+	t := g.precision
+	for i := range t {
+		for j := i + 1; j < len(t); j++ {
+			if t[i][j] == 0 {
+				continue
+			}
+			e := Edge{
+				I:      i,
+				J:      j,
+				Weight: -t[i][j] / math.Sqrt(t[i][i]*t[j][j]),
+			}
+			if len(g.labels) == len(t) {
+				e.From, e.To = g.labels[i], g.labels[j]
+			}
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// LogLikelihood returns the Gaussian log-likelihood n/2 (log det Θ - tr(S Θ)),
+// dropping the constant.
+func (g *GraphicalModel) LogLikelihood() float64 {
+	l, err := cholesky(mat64.NewDense(len(g.precision), len(g.precision), flatten(g.precision)))
+	if err != nil {
+		return math.Inf(-1)
+	}
+	ll := 0.0
+	for i := range l {
+		ll += 2 * math.Log(l[i][i])
+		for j := range g.precision {
+			ll -= g.sample[i][j] * g.precision[j][i]
+		}
+	}
+	return float64(g.n) / 2 * ll
+}
+
+func (g *GraphicalModel) String() string {
+	return fmt.Sprintf(`
+		Variables: %d
+		Rho: %v
+		Edges: %d
+		Log-likelihood: %v
+		Iterations: %d`,
+		len(g.precision),
+		g.rho,
+		len(g.Edges()),
+		round(g.LogLikelihood(), 3),
+		g.iterations,
+	)
+}
+
+// sampleCovariance returns the covariance of the columns of df, with divisor
+// n.
+func sampleCovariance(df *DataFrame) ([][]float64, error) {
+	n, p := df.Rows(), df.Cols()
+	if n < 2 {
+		return nil, fmt.Errorf("need at least two rows")
+	}
+	cols := make([][]float64, p)
+	for j := range cols {
+		cols[j] = subtractMean(df.GetCol(j))
+	}
+	s := make([][]float64, p)
+	for i := range s {
+		s[i] = make([]float64, p)
+		for j := 0; j <= i; j++ {
+			s[i][j] = dot(cols[i], cols[j]) / float64(n)
+			s[j][i] = s[i][j]
+		}
+	}
+	return s, nil
+}
+
+// glassoInit returns the cold start W = S + ρI, β = 0.
+func glassoInit(s [][]float64, rho float64) (w, b [][]float64) {
+	p := len(s)
+	w = make([][]float64, p)
+	b = make([][]float64, p)
+	for i := range w {
+		w[i] = append([]float64(nil), s[i]...)
+		w[i][i] += rho
+		b[i] = make([]float64, p)
+	}
+	return w, b
+}
+
+// glassoFit runs block coordinate descent from w and b, updating them in
+// place, where b[j] holds the lasso coefficients β of column j on the others.
+// The diagonal of w is reset to S + ρ, so a fit at another penalty can serve
+// as a warm start. It returns Θ and the largest number of sweeps made on any
+// component.
+func glassoFit(s [][]float64, rho float64, w, b [][]float64, maxIt int, tol float64) ([][]float64, int, error) {
+	p := len(s)
+
+	// convergence is measured against the average off-diagonal |S|
+	scale := 0.0
+	for i := range s {
+		for j := range s {
+			if i != j {
+				scale += math.Abs(s[i][j])
+			}
+		}
+	}
+	if p > 1 {
+		scale /= float64(p * (p - 1))
+	}
+
+	comp := glassoComponents(s, rho)
+	for i := range w {
+		if w[i][i] = s[i][i] + rho; w[i][i] <= 0 {
+			return nil, 0, fmt.Errorf("column %d is constant; rho must be positive", i)
+		}
+		for j := range w {
+			if i != j && comp[i] != comp[j] {
+				w[i][j], b[i][j] = 0, 0
+			}
+		}
+		b[i][i] = 0
+	}
+
+	members := make(map[int][]int)
+	for i, c := range comp {
+		members[c] = append(members[c], i)
+	}
+
+	iters := 0
+	for _, idx := range members {
+		if len(idx) == 1 {
+			continue
+		}
+		it := 0
+		for it < maxIt {
+			it++
+			change := 0.0
+			for _, j := range idx {
+				bj := b[j]
+				for inner := 0; inner < maxIt; inner++ {
+					maxDelta := 0.0
+					for _, k := range idx {
+						if k == j {
+							continue
+						}
+						z := s[k][j]
+						for _, l := range idx {
+							if l != j && l != k && bj[l] != 0 {
+								z -= w[k][l] * bj[l]
+							}
+						}
+						old := bj[k]
+						bj[k] = softThreshold(z, rho) / w[k][k]
+						maxDelta = math.Max(maxDelta, math.Abs(bj[k]-old))
+					}
+					if maxDelta <= tol {
+						break
+					}
+				}
+
+				// w12 = W11 β
+				for _, k := range idx {
+					if k == j {
+						continue
+					}
+					v := 0.0
+					for _, l := range idx {
+						if l != j && bj[l] != 0 {
+							v += w[k][l] * bj[l]
+						}
+					}
+					change += math.Abs(v - w[k][j])
+					w[k][j], w[j][k] = v, v
+				}
+			}
+			m := float64(len(idx))
+			if change/(m*(m-1)) <= tol*scale {
+				break
+			}
+		}
+		if it > iters {
+			iters = it
+		}
+	}
+
+	theta := make([][]float64, p)
+	for i := range theta {
+		theta[i] = make([]float64, p)
+	}
+	for j := range theta {
+		d := w[j][j]
+		for k := range theta {
+			if k != j {
+				d -= w[k][j] * b[j][k]
+			}
+		}
+		if d <= 0 {
+			return nil, 0, fmt.Errorf("covariance estimate is not positive definite")
+		}
+		theta[j][j] = 1 / d
+		for k := range theta {
+			if k != j {
+				theta[k][j] = -b[j][k] / d
+			}
+		}
+	}
+	// Θ is symmetric up to the convergence tolerance
+	for i := range theta {
+		for j := 0; j < i; j++ {
+			v := (theta[i][j] + theta[j][i]) / 2
+			theta[i][j], theta[j][i] = v, v
+		}
+	}
+	return theta, iters, nil
+}
+
+// glassoComponents labels the connected components of the graph with an edge
+// wherever |S_ij| > ρ.
+func glassoComponents(s [][]float64, rho float64) []int {
+	comp := make([]int, len(s))
+	for i := range comp {
+		comp[i] = -1
+	}
+	c := 0
+	for start := range s {
+		if comp[start] >= 0 {
+			continue
+		}
+		comp[start] = c
+		stack := []int{start}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for j := range s {
+				if comp[j] < 0 && math.Abs(s[i][j]) > rho {
+					comp[j] = c
+					stack = append(stack, j)
+				}
+			}
+		}
+		c++
+	}
+	return comp
+}
+
+func flatten(x [][]float64) []float64 {
+	var out []float64
+	for _, row := range x {
+		out = append(out, row...)
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// GlassoPath holds graphical lasso fits over a decreasing sequence of
+// penalties, each started from the solution at the previous one.
+type GlassoPath struct {
+	rhos   []float64
+	models []*GraphicalModel
+	data   *DataFrame
+}
+
+// GraphicalLassoPath fits the graphical lasso to the columns of df at each of
+// rhos, in decreasing order. If rhos is nil, 20 values are used, spaced evenly
+// on the log scale from rho_max, the largest off-diagonal |S_ij|, at which the
+// graph is empty, down to 0.1 rho_max.
+func GraphicalLassoPath(df *DataFrame, rhos []float64) (*GlassoPath, error) {
+	s, err := sampleCovariance(df)
+	if err != nil {
+		return nil, err
+	}
+	if rhos == nil {
+		rhos = rhoSequence(s, 20, 0.1)
+	} else {
+		rhos = append([]float64(nil), rhos...)
+		sort.Sort(sort.Reverse(sort.Float64Slice(rhos)))
+	}
+
+	models, err := glassoPath(df, s, rhos)
+	if err != nil {
+		return nil, err
+	}
+	return &GlassoPath{
+		rhos:   rhos,
+		models: models,
+		data:   df,
+	}, nil
+}
+
+func glassoPath(df *DataFrame, s [][]float64, rhos []float64) ([]*GraphicalModel, error) {
+	models := make([]*GraphicalModel, len(rhos))
+	var w, b [][]float64
+	for k, rho := range rhos {
+		if k == 0 {
+			w, b = glassoInit(s, rho)
+		}
+		m, err := glassoModel(df, s, rho, w, b)
+		if err != nil {
+			return nil, err
+		}
+		models[k] = m
+	}
+	return models, nil
+}
+
+// rhoSequence returns n penalties spaced evenly on the log scale from the
+// largest off-diagonal |S_ij| down to ratio times it.
+func rhoSequence(s [][]float64, n int, ratio float64) []float64 {
+	rmax := 0.0
+	for i := range s {
+		for j := range s {
+			if i != j {
+				rmax = math.Max(rmax, math.Abs(s[i][j]))
+			}
+		}
+	}
+	rhos := make([]float64, n)
+	for k := range rhos {
+		rhos[k] = rmax * math.Pow(ratio, float64(k)/float64(n-1))
+	}
+	return rhos
+}
+
+// Rhos returns the decreasing penalties of the path.
+func (g *GlassoPath) Rhos() []float64 { return g.rhos }
+
+// Model returns the fit at the ith penalty.
+func (g *GlassoPath) Model(i int) *GraphicalModel { return g.models[i] }
+
+// Edges returns the number of edges at each penalty.
+func (g *GlassoPath) Edges() []int {
+	edges := make([]int, len(g.models))
+	for k, m := range g.models {
+		edges[k] = len(m.Edges())
+	}
+	return edges
+}
+
+// LogLikelihoods returns the log-likelihood at each penalty.
+func (g *GlassoPath) LogLikelihoods() []float64 {
+	ll := make([]float64, len(g.models))
+	for k, m := range g.models {
+		ll[k] = m.LogLikelihood()
+	}
+	return ll
+}
+
+// EBIC returns the index of the penalty minimizing the extended BIC (Foygel
+// and Drton, 2010)
+//
+// -2 l + |E| log n + 4 gamma |E| log p
+//
+// and the criterion at each penalty. gamma = 0 is the BIC; gamma = 0.5 is
+// the usual choice for sparse graphs with many variables.
+func (g *GlassoPath) EBIC(gamma float64) (int, []float64) {
+	n, p := float64(g.data.Rows()), float64(g.data.Cols())
+	ebic := make([]float64, len(g.models))
+	for k, m := range g.models {
+		e := float64(len(m.Edges()))
+		ebic[k] = -2*m.LogLikelihood() + e*math.Log(n) + 4*gamma*e*math.Log(p)
+	}
+	best := 0
+	for k, v := range ebic {
+		if v < ebic[best] {
+			best = k
+		}
+	}
+	return best, ebic
+}
+
+// StARS returns the index of the penalty chosen by the stability approach to
+// regularization selection (Liu, Roeder and Wasserman, 2010), and the
+// instability at each penalty.
+//
+// The path is refit on subsamples of the rows, drawn without replacement from
+// a generator seeded with seed, of size 10 sqrt(n) (0.8 n for n <= 144). With
+// theta_ij the fraction of subsamples containing edge ij, the instability is
+// the mean of 2 theta_ij (1 - theta_ij) over the pairs, made monotone in the
+// penalty by taking its running maximum from the largest penalty down. The
+// smallest penalty whose instability is at most beta (0.1 if not positive, as
+// in R's huge) is chosen, or the largest penalty if none is.
+func (g *GlassoPath) StARS(subsamples int, beta float64, seed int64) (int, []float64, error) {
+	if subsamples < 2 {
+		return 0, nil, fmt.Errorf("need at least two subsamples")
+	}
+	if beta <= 0 {
+		beta = 0.1
+	}
+
+	n, p := g.data.Rows(), g.data.Cols()
+	if p < 2 {
+		return 0, nil, fmt.Errorf("need at least two columns")
+	}
+	size := int(10 * math.Sqrt(float64(n)))
+	if n <= 144 {
+		size = int(0.8 * float64(n))
+	}
+
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = g.data.GetRow(i)
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	counts := make([][][]float64, len(g.rhos))
+	for k := range counts {
+		counts[k] = make([][]float64, p)
+		for i := range counts[k] {
+			counts[k][i] = make([]float64, p)
+		}
+	}
+	for r := 0; r < subsamples; r++ {
+		sub := subsetRows(g.data, rows, rnd.Perm(n)[:size])
+		s, err := sampleCovariance(sub)
+		if err != nil {
+			return 0, nil, err
+		}
+		models, err := glassoPath(sub, s, g.rhos)
+		if err != nil {
+			return 0, nil, err
+		}
+		for k, m := range models {
+			for _, e := range m.Edges() {
+				counts[k][e.I][e.J]++
+			}
+		}
+	}
+
+	instability := make([]float64, len(g.rhos))
+	pairs := float64(p * (p - 1) / 2)
+	for k := range instability {
+		d := 0.0
+		for i := range counts[k] {
+			for j := i + 1; j < p; j++ {
+				theta := counts[k][i][j] / float64(subsamples)
+				d += 2 * theta * (1 - theta)
+			}
+		}
+		instability[k] = d / pairs
+		if k > 0 {
+			instability[k] = math.Max(instability[k], instability[k-1])
+		}
+	}
+
+	best := 0
+	for k, d := range instability {
+		if d <= beta {
+			best = k
+		}
+	}
+	return best, instability, nil
+}
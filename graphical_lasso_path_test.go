@@ -0,0 +1,74 @@
+package glasso
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestGraphicalLassoPath(t *testing.T) {
+	df := chainData(300, 6, 4)
+	path, err := GraphicalLassoPath(df, nil)
+	assert.Equal(t, nil, err)
+
+	rhos := path.Rhos()
+	assert.Equal(t, 20, len(rhos))
+	assert.Equal(t, true, sort.IsSorted(sort.Reverse(sort.Float64Slice(rhos))))
+
+	edges, ll := path.Edges(), path.LogLikelihoods()
+	assert.Equal(t, 0, edges[0])
+	assert.Equal(t, true, edges[len(edges)-1] >= 5)
+	assert.Equal(t, true, ll[len(ll)-1] > ll[0])
+
+	// warm starts reach the same solutions as cold starts
+	for _, k := range []int{3, 10, 19} {
+		cold, err := GraphicalLasso(df, rhos[k])
+		assert.Equal(t, nil, err)
+		for i := range cold.precision {
+			assertNear(t, cold.precision[i], path.Model(k).precision[i], 1e-4)
+		}
+	}
+
+	// given penalties are sorted
+	path, err = GraphicalLassoPath(df, []float64{0.05, 0.2, 0.1})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []float64{0.2, 0.1, 0.05}, path.Rhos())
+}
+
+func TestGraphicalLassoSelection(t *testing.T) {
+	df := chainData(500, 8, 5)
+	path, err := GraphicalLassoPath(df, nil)
+	assert.Equal(t, nil, err)
+
+	chain := func(m *GraphicalModel) bool {
+		adj := m.Adjacency()
+		for j := 1; j < 8; j++ {
+			if !adj[j-1][j] {
+				return false
+			}
+		}
+		return true
+	}
+
+	best, ebic := path.EBIC(0.5)
+	assert.Equal(t, 20, len(ebic))
+	for _, v := range ebic {
+		assert.Equal(t, true, ebic[best] <= v)
+	}
+	assert.Equal(t, true, chain(path.Model(best)))
+
+	best, instability, err := path.StARS(10, 0, 1)
+	assert.Equal(t, nil, err)
+	for k := 1; k < len(instability); k++ {
+		assert.Equal(t, true, instability[k] >= instability[k-1])
+	}
+	assert.Equal(t, true, instability[best] <= 0.1)
+	assert.Equal(t, true, chain(path.Model(best)))
+
+	// the subsamples are reproducible from the seed
+	again, instability2, err := path.StARS(10, 0, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, best, again)
+	assert.Equal(t, instability, instability2)
+}
@@ -0,0 +1,117 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+// columns following x_j = 0.6 x_j-1 + e_j, whose precision matrix is
+// tridiagonal: each column depends only on its neighbours
+func chainData(n, p int, seed int64) *DataFrame {
+	rnd := rand.New(rand.NewSource(seed))
+	rows := make([][]float64, n)
+	labels := make([]string, p)
+	for j := range labels {
+		labels[j] = string('a' + rune(j))
+	}
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		rows[i][0] = rnd.NormFloat64()
+		for j := 1; j < p; j++ {
+			rows[i][j] = 0.6*rows[i][j-1] + rnd.NormFloat64()
+		}
+	}
+	return NewDataFrame(rows, labels)
+}
+
+// without a penalty the estimate is the inverse of the sample covariance
+func TestGraphicalLassoUnpenalized(t *testing.T) {
+	df := chainData(200, 5, 1)
+	g, err := GraphicalLasso(df, 0)
+	assert.Equal(t, nil, err)
+
+	s, err := sampleCovariance(df)
+	assert.Equal(t, nil, err)
+	inv := &mat64.Dense{}
+	assert.Equal(t, nil, inv.Inverse(mat64.NewDense(5, 5, flatten(s))))
+
+	prec := g.Precision().Data()
+	for i := 0; i < 5; i++ {
+		assertNear(t, mat64.Row(nil, i, inv), mat64.Row(nil, i, prec), 1e-4)
+		assertNear(t, s[i], g.Covariance().GetRow(i), 1e-6)
+	}
+}
+
+// the solution satisfies the optimality conditions W_ij = S_ij + ρ sign(Θ_ij)
+// where Θ_ij != 0 and |W_ij - S_ij| <= ρ elsewhere, with W Θ = I
+func TestGraphicalLassoKKT(t *testing.T) {
+	df := chainData(300, 8, 2)
+	rho := 0.1
+	g, err := GraphicalLasso(df, rho)
+	assert.Equal(t, nil, err)
+	s, _ := sampleCovariance(df)
+
+	w, theta := g.covariance, g.precision
+	zeros := 0
+	for i := range w {
+		for j := range w {
+			if i == j {
+				assertNear(t, []float64{s[i][i] + rho}, []float64{w[i][i]}, 1e-10)
+				continue
+			}
+			if theta[i][j] != 0 {
+				assertNear(t, []float64{s[i][j] + rho*sign(theta[i][j])}, []float64{w[i][j]}, 1e-4)
+			} else {
+				zeros++
+				assert.Equal(t, true, math.Abs(w[i][j]-s[i][j]) <= rho+1e-4)
+			}
+		}
+	}
+	assert.Equal(t, true, zeros > 0)
+
+	prod := &mat64.Dense{}
+	prod.Mul(g.Covariance().Data(), g.Precision().Data())
+	for i := 0; i < 8; i++ {
+		want := make([]float64, 8)
+		want[i] = 1
+		assertNear(t, want, mat64.Row(nil, i, prod), 1e-3)
+	}
+}
+
+func TestGraphicalLassoEdges(t *testing.T) {
+	df := chainData(2000, 6, 3)
+	g, err := GraphicalLasso(df, 0.1)
+	assert.Equal(t, nil, err)
+
+	adj := g.Adjacency()
+	for j := 1; j < 6; j++ {
+		assert.Equal(t, true, adj[j-1][j])
+		assert.Equal(t, true, adj[j][j-1])
+	}
+	for _, e := range g.Edges() {
+		assert.Equal(t, true, e.I < e.J)
+		assert.Equal(t, df.Labels()[e.I], e.From)
+		assert.Equal(t, df.Labels()[e.J], e.To)
+		if e.J == e.I+1 {
+			assert.Equal(t, true, e.Weight > 0.3)
+		}
+	}
+	assert.Equal(t, df.Labels(), g.Precision().Labels())
+	assert.Equal(t, df.Labels(), g.Covariance().Labels())
+
+	// a penalty above every covariance leaves no edges
+	big, err := GraphicalLasso(df, 100)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(big.Edges()))
+	s, _ := sampleCovariance(df)
+	for i := range s {
+		assertNear(t, []float64{1 / (s[i][i] + 100)}, []float64{big.precision[i][i]}, 1e-12)
+	}
+
+	_, err = GraphicalLasso(df, -1)
+	assert.NotEqual(t, nil, err)
+}
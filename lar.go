@@ -1,104 +1,327 @@
 package glasso
 
-/*
 import (
-	"sort"
+	"math"
 
 	"github.com/gonum/matrix/mat64"
 )
 
-// Analogous to least squares boosting (trees = predictors)
+// Least angle regression (Efron, Hastie, Johnstone & Tibshirani 2004)
+//
+// Start with all coefficients at 0 and find the predictor most correlated
+// with y. Move its coefficient towards the least squares fit until some other
+// predictor has as much correlation with the residual, then move both along
+// their equiangular direction until a third one catches up, and so on.
+//
+// With the lasso modification, a coefficient that hits zero is dropped from
+// the active set and the direction recomputed, which gives the whole lasso
+// path. Coefficients are linear in between the breakpoints, so recording the
+// breakpoints records the path.
 type LeastAngle struct {
-	x        *DataFrame
-	epsilon  float64 // how much to increase each beta by
-	delta    float64 // limit to the max correlation amongst variables
-	y        []float64
-	betas    []float64
-	p        int
-	firstRun bool
+	betas []float64
 }
 
-// Start with initial residual r = y, and β1 = β2 = · · · = βp = 0.
-// Find the predictor Zj (j = 1, . . . , p) most correlated with r
-// Update βj ← βj + δj
-// Set r ← r − δjZj
-// Repeat
-//
-// Pretty much the same as least squares boosting
-func (f *LeastAngle) Train(y []float64) error {
-	// first we need to standardize the matrix and scale y
-	// and set up variables
-	f.x.Standardize() // make sure x_j_bar = 0
-	n, p := f.x.rows, f.x.cols
-
-	// set all betas to 0
-	f.betas = rep(0.0, p)
-
-	// center y
-	r := subtractMean(y) // make sure y_bar = 0
-	x := mat64.NewDense(n, p, rep(0.0, n*p))
-	f.firstRun = true
-
-	// find the most correlated variable
-	cors := make([]float64, 0, f.x.cols)
-	for i := 0; i < f.x.cols; i++ {
-		cors[i] = cor(f.x.data.Col(nil, i), y)
+func (l *LeastAngle) Predict(x []float64) float64 {
+	return l.betas[0] + sum(prod(x, l.betas[1:]))
+}
+
+// LarsTrainer fits the least angle regression path.
+type LarsTrainer struct {
+	lasso    bool
+	maxSteps int
+}
+
+// NewLarsTrainer returns a LARS trainer. If lasso is set, variables are
+// dropped when their coefficient crosses zero. maxSteps bounds the number of
+// breakpoints; if it is not positive, min(p, n-1) steps are allowed for LARS
+// and 8 * min(p, n-1) for the lasso.
+func NewLarsTrainer(lasso bool, maxSteps int) *LarsTrainer {
+	return &LarsTrainer{
+		lasso:    lasso,
+		maxSteps: maxSteps,
+	}
+}
+
+// Train fits the path and returns the model at its last breakpoint.
+func (l *LarsTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	path, err := l.Path(x, y)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, summary := path.Fit(path.Steps() - 1)
+	return model, summary, nil
+}
+
+// Path computes every breakpoint of the coefficient path.
+func (l *LarsTrainer) Path(x *DataFrame, y []float64) (*LarsPath, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	cols, means, sds := scaleCols(x)
+	ybar := mean(y)
+	yc := subtractMean(y)
+
+	maxVars := p
+	if n-1 < maxVars {
+		maxVars = n - 1
+	}
+	maxSteps := l.maxSteps
+	if maxSteps <= 0 {
+		maxSteps = maxVars
+		if l.lasso {
+			maxSteps *= 8
+		}
+	}
+
+	path := &LarsPath{
+		data:     x.Copy(),
+		response: response,
 	}
-	maxCor := max(cors)
-	maxIdx := sort.SearchFloat64s(cors, maxCor)
 
-	stopCor := 1
-	for f.stop() {
-		x.SetCol(maxIdx, f.x.data.Col(nil, maxIdx))
+	var (
+		mu     = rep(0.0, n) // current fit
+		b      = rep(0.0, p) // coefficients on the standardized scale
+		c      = make([]float64, p)
+		active []int
+		in     = make([]bool, p)
+		drop   = -1
+	)
+	path.record(b, means, sds, ybar, -1, -1)
+
+	for step := 0; step < maxSteps; step++ {
+		r := diff(yc, mu)
+		for j := range cols {
+			c[j] = dot(cols[j], r)
+		}
+
+		// bring in the most correlated inactive variable, unless one was
+		// just dropped, in which case the direction is recomputed first
+		var bigC float64
+		added := -1
+		if drop < 0 {
+			for j := range cols {
+				if !in[j] && (added < 0 || math.Abs(c[j]) > math.Abs(c[added])) {
+					added = j
+				}
+			}
+			if added < 0 || math.Abs(c[added]) < 1e-12 {
+				break
+			}
+			bigC = math.Abs(c[added])
+			in[added] = true
+			active = append(active, added)
+		} else {
+			for _, j := range active {
+				bigC = math.Max(bigC, math.Abs(c[j]))
+			}
+		}
+		drop = -1
 
-		delta := f.epsilon * sign(sum(prod(x.Col(nil, maxIdx), r)))
-		f.betas[maxIdx] += delta
+		u, w, aa, err := equiangular(cols, c, active)
+		if err != nil {
+			break
+		}
 
-		r = diff(y, multSlice(x.Col(nil, maxIdx), delta))
+		// the step length is the smallest at which an inactive variable
+		// catches up with the active ones
+		gamma := bigC / aa
+		if len(active) < maxVars {
+			for j := range cols {
+				if in[j] {
+					continue
+				}
+				a := dot(cols[j], u)
+				for _, g := range []float64{(bigC - c[j]) / (aa - a), (bigC + c[j]) / (aa + a)} {
+					if g > 1e-12 && g < gamma {
+						gamma = g
+					}
+				}
+			}
+		}
 
-		maxCor = cor(f.x.data.Col(nil, maxIdx), r)
-		for i := 0; i < f.x.Cols && i != maxIdx; i++ {
-			c := cor(f.x.data.Col(nil, i), r)
-			if c >= maxCor {
-				maxIdx = i
+		// lasso modification: stop where an active coefficient changes sign
+		if l.lasso {
+			for k, j := range active {
+				d := sign(c[j]) * w[k]
+				if g := -b[j] / d; g > 1e-12 && g < gamma {
+					gamma = g
+					drop = j
+				}
 			}
 		}
+
+		for i := range mu {
+			mu[i] += gamma * u[i]
+		}
+		for k, j := range active {
+			b[j] += gamma * sign(c[j]) * w[k]
+		}
+
+		if drop >= 0 {
+			b[drop] = 0
+			in[drop] = false
+			for k, j := range active {
+				if j == drop {
+					active = append(active[:k], active[k+1:]...)
+					break
+				}
+			}
+		}
+		path.record(b, means, sds, ybar, added, drop)
+
+		// a full step with every variable in is the least squares fit
+		if drop < 0 && len(active) >= maxVars && gamma == bigC/aa {
+			break
+		}
+	}
+
+	if err := path.score(); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// equiangular returns the unit vector u making equal angles with the signed
+// active columns, along with the weights w such that u = X_A w and the
+// normalizing constant A_A:
+//
+// G_A = X_A' X_A
+// A_A = (1' G_A^-1 1)^(-1/2)
+// w = A_A G_A^-1 1
+func equiangular(cols [][]float64, c []float64, active []int) (u, w []float64, aa float64, err error) {
+	k := len(active)
+	g := mat64.NewDense(k, k, nil)
+	for a, i := range active {
+		for b, j := range active {
+			g.Set(a, b, sign(c[i])*sign(c[j])*dot(cols[i], cols[j]))
+		}
 	}
 
-	// update beta_j
-	// beta_j = beta_j + delta_j
-	// where delta_j = epsilon * sign(y, x_j)
-	x.SetCol(maxIdx, f.x.data.Col(nil, maxIdx))
-	//ols := NewOLS(&DataFrame{x, n, p, nil})
-	//ols.Train(r)
+	ginv := &mat64.Dense{}
+	if err = ginv.Solve(g, mat64.NewDense(k, 1, rep(1.0, k))); err != nil {
+		return nil, nil, 0, err
+	}
+	w = mat64.Col(nil, 0, ginv)
 
-	// update beta
-	delta := f.epsilon * sign(sum(prod(x.Col(nil, maxIdx), r)))
-	f.betas[maxIdx] += delta
+	aa = 1 / math.Sqrt(sum(w))
+	w = multSlice(w, aa)
 
-	// set r = r - delta_j * x_j
-	r = diff(r, multSlice(x.Col(nil, maxIdx), delta))
+	u = make([]float64, len(cols[0]))
+	for a, j := range active {
+		s := sign(c[j]) * w[a]
+		for i, v := range cols[j] {
+			u[i] += s * v
+		}
+	}
+	return u, w, aa, nil
+}
 
-	return nil
+// LarsPath holds the breakpoints of a least angle regression path.
+type LarsPath struct {
+	betas    [][]float64
+	entered  []int
+	left     []int
+	df       []int
+	rss      []float64
+	cp       []float64
+	aic      []float64
+	data     *DataFrame
+	response []float64
 }
 
-// we continue until the residuals are uncorrelated with the predictors up
-// to a certain delta
-func (f *LeastAngle) isCorrelation(y []float64) bool {
-	if f.firstRun {
-		f.firstRun = false
-		return true
+func (l *LarsPath) record(b, means, sds []float64, ybar float64, entered, left int) {
+	betas := unscaleBetas(b, means, sds, ybar)
+	_, residuals := linearFit(l.data, betas, l.response)
+
+	df := 1
+	for _, v := range b {
+		if v != 0 {
+			df++
+		}
 	}
 
-	// find the most correlated variable
-	cors := make([]float64, 0, f.x.cols)
-	for i := 0; i < f.x.cols; i++ {
-		cors[i] = cor(f.x.data.Col(nil, i), y)
+	l.betas = append(l.betas, betas)
+	l.entered = append(l.entered, entered)
+	l.left = append(l.left, left)
+	l.df = append(l.df, df)
+	l.rss = append(l.rss, dot(residuals, residuals))
+}
+
+// Mallows' Cp and AIC at each breakpoint, with sigma^2 estimated from the
+// full least squares fit:
+//
+// Cp = RSS / sigma^2 - n + 2 df
+// AIC = n log(RSS / n) + 2 df
+//
+// Cp is only defined when n > p + 1, and is NaN otherwise.
+func (l *LarsPath) score() error {
+	n, p := l.data.Rows(), l.data.Cols()
+	sigma2 := math.NaN()
+	if n > p+1 {
+		_, ols, err := NewOlsTrainer().Train(l.data.Copy(), l.response)
+		if err != nil {
+			return err
+		}
+		sigma2 = ols.SumOfSquares() / float64(n-p-1)
 	}
-	if max(cors) < f.delta {
-		return false
+
+	l.cp = make([]float64, len(l.rss))
+	l.aic = make([]float64, len(l.rss))
+	for i, rss := range l.rss {
+		df := float64(l.df[i])
+		l.cp[i] = rss/sigma2 - float64(n) + 2*df
+		l.aic[i] = float64(n)*math.Log(rss/float64(n)) + 2*df
 	}
-	return true
+	return nil
 }
 
-*/
+// Steps returns the number of breakpoints, including the empty model.
+func (l *LarsPath) Steps() int { return len(l.betas) }
+
+// Coefficients returns the coefficients at the ith breakpoint, on the
+// original scale and with the intercept first.
+func (l *LarsPath) Coefficients(i int) []float64 { return l.betas[i] }
+
+// Entered returns the column that joined the active set on the way to each
+// breakpoint, or -1 if none did.
+func (l *LarsPath) Entered() []int { return l.entered }
+
+// Left returns the column dropped from the active set at each breakpoint
+// because its coefficient crossed zero, or -1 if none was.
+func (l *LarsPath) Left() []int { return l.left }
+
+// Df returns the number of nonzero coefficients, plus the intercept, at each
+// breakpoint.
+func (l *LarsPath) Df() []int { return l.df }
+
+// RSS returns the residual sum of squares at each breakpoint.
+func (l *LarsPath) RSS() []float64 { return l.rss }
+
+// Cp returns Mallows' Cp at each breakpoint.
+func (l *LarsPath) Cp() []float64 { return l.cp }
+
+// AIC returns the AIC at each breakpoint.
+func (l *LarsPath) AIC() []float64 { return l.aic }
+
+// Fit returns the model and summary at the ith breakpoint.
+func (l *LarsPath) Fit(i int) (Model, Summary) {
+	betas := l.betas[i]
+	fitted, residuals := linearFit(l.data, betas, l.response)
+
+	return &LeastAngle{
+		betas: betas,
+	}, OlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  l.response,
+		n:         l.data.Rows(),
+		p:         l.data.Cols(),
+		data:      withIntercept(l.data),
+	}
+}
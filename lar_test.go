@@ -0,0 +1,51 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestLars(t *testing.T) {
+	path, err := NewLarsTrainer(false, 0).Path(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	// one variable enters at each step, ending at least squares
+	assert.Equal(t, 4, path.Steps())
+	assert.Equal(t, []int{-1, 0, 1, 2}, path.Entered())
+	assert.Equal(t, []int{1, 2, 3, 4}, path.Df())
+
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertEqual(t, path.Coefficients(3), ols.Coefficients())
+
+	// Cp of the full model is p + 1
+	assert.Equal(t, 4.0, round(path.Cp()[3], 3))
+	assert.Equal(t, round(path.AIC()[3], 3), round(21*math.Log(ols.SumOfSquares()/21)+8, 3))
+}
+
+func TestLarsLasso(t *testing.T) {
+	df := NewDataFrame(data)
+	path, err := NewLarsTrainer(true, 0).Path(df, y)
+	assert.Equal(t, nil, err)
+
+	// every breakpoint is a lasso solution, at the lambda where the
+	// largest correlation with the residual equals lambda
+	cols, _, _ := scaleCols(df)
+	for i := 1; i < path.Steps(); i++ {
+		_, residuals := linearFit(df, path.Coefficients(i), y)
+		lambda := 0.0
+		for _, col := range cols {
+			lambda = math.Max(lambda, math.Abs(dot(col, residuals))/21)
+		}
+
+		_, lasso, err := NewLassoTrainer(lambda).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertNear(t, path.Coefficients(i), lasso.Coefficients(), 0.05)
+	}
+
+	model, _, err := NewLarsTrainer(true, 1).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, nil, model)
+}
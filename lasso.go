@@ -0,0 +1,149 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMaxIterations bounds the number of full passes made by the
+// coordinate descent solvers.
+const DefaultMaxIterations = 10000
+
+// Lasso regression shrinks the coefficients with an L1 penalty, which sets
+// some of them exactly to zero:
+//
+// Beta_lasso = min( 1/2n \sum(y_i - \beta_0 - \sum x_ij * \beta_j)^2 + \lambda \sum |\beta_j| )
+//
+// The intercept is not penalized. The coefficients are fit on standardized
+// columns and reported on the original scale.
+type Lasso struct {
+	betas []float64
+}
+
+func (l *Lasso) Predict(x []float64) float64 {
+	return l.betas[0] + sum(prod(x, l.betas[1:]))
+}
+
+type lassoTrainer struct {
+	lambda float64
+}
+
+// NewLassoTrainer returns a Trainer for the lasso with penalty lambda.
+//
+// lambda = 0 equals the least squares solution
+// larger lambda sets more of the coefficients to 0
+func NewLassoTrainer(lambda float64) Trainer {
+	return &lassoTrainer{
+		lambda: lambda,
+	}
+}
+
+// Cyclic coordinate descent: each coefficient in turn is set to the
+// soft-thresholded univariate least squares fit on the partial residual
+//
+// \beta_j = S(1/n \sum x_ij r_ij, \lambda)
+// S(z, \gamma) = sign(z)(|z| - \gamma)_+
+func (l *lassoTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if l.lambda < 0 {
+		return nil, nil, fmt.Errorf("lambda must be non-negative")
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	// standardize x and center y, so the intercept drops out
+	cols, means, sds := scaleCols(x)
+	r := subtractMean(y)
+	b := rep(0.0, p)
+
+	// convergence is measured relative to the variance of y
+	tol := DefaultTolerance * dot(r, r) / float64(n)
+	coordinateDescent(cols, colSquares(cols), r, b, l.lambda, 1, nil, DefaultMaxIterations, tol)
+
+	betas := unscaleBetas(b, means, sds, mean(y))
+	fitted, residuals := linearFit(x, betas, response)
+
+	return &Lasso{
+		betas: betas,
+	}, OlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		n:         n,
+		p:         p,
+		data:      withIntercept(x),
+	}, nil
+}
+
+// soft thresholding operator
+// S(z, \gamma) = sign(z)(|z| - \gamma)_+
+func softThreshold(z, gamma float64) float64 {
+	if math.Abs(z) <= gamma {
+		return 0
+	}
+	return sign(z) * (math.Abs(z) - gamma)
+}
+
+// colSquares returns 1/n \sum x_ij^2 for each column.
+func colSquares(cols [][]float64) []float64 {
+	xx := make([]float64, len(cols))
+	for j, col := range cols {
+		xx[j] = dot(col, col) / float64(len(col))
+	}
+	return xx
+}
+
+// coordinateDescent minimizes the elastic net objective
+//
+// 1/2n ||r||^2 + \lambda \sum (\alpha |\beta_j| + (1 - \alpha)/2 \beta_j^2)
+//
+// over the centered columns, updating betas and the residuals r in place.
+// Only the columns in active are visited; if active is nil every column is.
+// Iteration stops once no coefficient moves the objective by more than tol,
+// and the number of passes made is returned.
+func coordinateDescent(cols [][]float64, xx, r, betas []float64, lambda, alpha float64, active []int, maxIt int, tol float64) int {
+	if active == nil {
+		active = make([]int, len(cols))
+		for j := range active {
+			active[j] = j
+		}
+	}
+
+	n := float64(len(r))
+	l1 := lambda * alpha
+	l2 := lambda * (1 - alpha)
+
+	it := 0
+	for it < maxIt {
+		it++
+		maxChange := 0.0
+		for _, j := range active {
+			if xx[j] == 0 {
+				continue
+			}
+			old := betas[j]
+			z := dot(cols[j], r)/n + xx[j]*old
+			betas[j] = softThreshold(z, l1) / (xx[j] + l2)
+
+			delta := betas[j] - old
+			if delta == 0 {
+				continue
+			}
+			for i, v := range cols[j] {
+				r[i] -= v * delta
+			}
+			if change := xx[j] * delta * delta; change > maxChange {
+				maxChange = change
+			}
+		}
+		if maxChange < tol {
+			break
+		}
+	}
+	return it
+}
@@ -0,0 +1,37 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestLasso(t *testing.T) {
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	// no penalty gives back the least squares fit
+	{
+		_, summary, err := NewLassoTrainer(0).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertEqual(t, summary.Coefficients(), ols.Coefficients())
+	}
+
+	// a large penalty leaves only the intercept
+	{
+		model, summary, err := NewLassoTrainer(100).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertEqual(t, summary.Coefficients(), []float64{mean(y), 0, 0, 0})
+		assert.Equal(t, round(model.Predict(data[0]), 3), round(mean(y), 3))
+	}
+
+	// in between, acid concentration is dropped first
+	{
+		_, summary, err := NewLassoTrainer(1).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		betas := summary.Coefficients()
+		assert.Equal(t, 0.0, betas[3])
+		assert.T(t, betas[1] > 0 && betas[2] > 0)
+		assertEqual(t, summary.Yhat(), diff(y, summary.Residuals()))
+	}
+}
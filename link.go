@@ -0,0 +1,143 @@
+package glasso
+
+import (
+	"math"
+)
+
+// A Link relates the mean mu of the response to the linear predictor
+// eta = X beta through eta = g(mu).
+type Link struct {
+	Name         string
+	LinkFn       evalFn // eta = g(mu)
+	InverseFn    evalFn // mu = g^-1(eta)
+	DerivativeFn evalFn // d mu / d eta, as a function of eta
+}
+
+// NewLink returns a Link with the given link, inverse link and derivative of
+// the inverse link.
+func NewLink(name string, link, inverse, derivative evalFn) Link {
+	return Link{
+		Name:         name,
+		LinkFn:       link,
+		InverseFn:    inverse,
+		DerivativeFn: derivative,
+	}
+}
+
+var (
+	Logit          = NewLink("logit", logitLink, logitInverse, logitDerivative)
+	Probit         = NewLink("probit", probitLink, probitInverse, probitDerivative)
+	CLogLog        = NewLink("cloglog", cloglogLink, cloglogInverse, cloglogDerivative)
+	Log            = NewLink("log", math.Log, math.Exp, math.Exp)
+	Identity       = NewLink("identity", identity, identity, one)
+	Inverse        = NewLink("inverse", inverse, inverse, inverseDerivative)
+	InverseSquared = NewLink("1/mu^2", inverseSquaredLink, inverseSquaredInverse, inverseSquaredDerivative)
+	Sqrt           = NewLink("sqrt", math.Sqrt, square, double)
+)
+
+// keeps the derivative of links onto (0, 1) away from 0, where the IRLS
+// working response blows up
+const linkEpsilon = 2.220446e-16
+
+// bounds on eta for the logit and probit inverses, so mu stays strictly
+// inside (0, 1)
+const (
+	logitThreshold  = 30
+	probitThreshold = 8.125890664701906 // -qnorm(linkEpsilon)
+)
+
+func clamp(x, lo, hi float64) float64 {
+	return math.Min(math.Max(x, lo), hi)
+}
+
+func identity(x float64) float64 { return x }
+func one(x float64) float64      { return 1 }
+func inverse(x float64) float64  { return 1 / x }
+func square(x float64) float64   { return x * x }
+func double(x float64) float64   { return 2 * x }
+
+// -------------------------- //
+//          Logit
+// -------------------------- //
+
+// log(mu / (1 - mu))
+func logitLink(x float64) float64 {
+	return math.Log(x / (1 - x))
+}
+
+// logistic function: 1 / (1 + exp(-x))
+func logitInverse(x float64) float64 {
+	return 1 / (1 + math.Exp(-clamp(x, -logitThreshold, logitThreshold)))
+}
+
+// derivative of logistic f(x) = f(x) * (1 - f(x))
+func logitDerivative(x float64) float64 {
+	l := logitInverse(x)
+	return math.Max(l*(1-l), linkEpsilon)
+}
+
+// -------------------------- //
+//          Probit
+// -------------------------- //
+
+// quantile function of the standard normal
+func probitLink(x float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*x-1)
+}
+
+// standard normal cdf
+func probitInverse(x float64) float64 {
+	return 0.5 * math.Erfc(-clamp(x, -probitThreshold, probitThreshold)/math.Sqrt2)
+}
+
+// standard normal density
+func probitDerivative(x float64) float64 {
+	return math.Max(math.Exp(-x*x/2)/math.Sqrt(2*math.Pi), linkEpsilon)
+}
+
+// -------------------------- //
+//    Complementary log-log
+// -------------------------- //
+
+// log(-log(1 - mu))
+func cloglogLink(x float64) float64 {
+	return math.Log(-math.Log(1 - x))
+}
+
+// 1 - exp(-exp(x))
+func cloglogInverse(x float64) float64 {
+	return clamp(-math.Expm1(-math.Exp(x)), linkEpsilon, 1-linkEpsilon)
+}
+
+// exp(x - exp(x))
+func cloglogDerivative(x float64) float64 {
+	return math.Max(math.Exp(x-math.Exp(x)), linkEpsilon)
+}
+
+// -------------------------- //
+//          Inverse
+// -------------------------- //
+
+// derivative of 1/x: -1/x^2
+func inverseDerivative(x float64) float64 {
+	return -1 / (x * x)
+}
+
+// -------------------------- //
+//      Inverse squared
+// -------------------------- //
+
+// 1 / mu^2
+func inverseSquaredLink(x float64) float64 {
+	return 1 / (x * x)
+}
+
+// 1 / sqrt(x)
+func inverseSquaredInverse(x float64) float64 {
+	return 1 / math.Sqrt(x)
+}
+
+// derivative of 1 / sqrt(x): -(x ^ -3/2) / 2
+func inverseSquaredDerivative(x float64) float64 {
+	return -0.5 * math.Pow(x, -1.5)
+}
@@ -0,0 +1,410 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Multinomial logistic regression models the probability of each of K
+// classes as
+//
+// P(y = k | x) = exp(b_k0 + x b_k) / sum_j exp(b_j0 + x b_j)
+//
+// with the coefficients of a reference class fixed at zero, so b_k are the
+// log odds of class k against the reference. The coefficients maximize the
+// penalized log-likelihood
+//
+// sum_i log P(y_i | x_i) - lambda / 2 sum_k ||b_k||^2
+//
+// by Newton-Raphson, where the intercepts b_k0 are not penalized.
+type Multinomial struct {
+	levels    []float64
+	reference int
+	betas     [][]float64 // one row per level, intercept first
+}
+
+// Probabilities returns the probability of each class, in the order of
+// Levels.
+func (m *Multinomial) Probabilities(x []float64) []float64 {
+	eta := make([]float64, len(m.levels))
+	for k, b := range m.betas {
+		eta[k] = b[0] + sum(prod(x, b[1:]))
+	}
+	return softmax(eta)
+}
+
+// Classify returns the most probable class.
+func (m *Multinomial) Classify(x []float64) float64 {
+	return m.levels[argmax(m.Probabilities(x))]
+}
+
+// Predict returns the most probable class.
+func (m *Multinomial) Predict(x []float64) float64 {
+	return m.Classify(x)
+}
+
+// Levels returns the classes, in increasing order.
+func (m *Multinomial) Levels() []float64 { return m.levels }
+
+// Reference returns the reference class.
+func (m *Multinomial) Reference() float64 { return m.levels[m.reference] }
+
+// Coefficients returns the intercept and coefficients of the log odds of the
+// class against the reference, or nil if it is not a class.
+func (m *Multinomial) Coefficients(level float64) []float64 {
+	for k, l := range m.levels {
+		if l == level {
+			return m.betas[k]
+		}
+	}
+	return nil
+}
+
+// MultinomialTrainer fits multinomial logistic regression.
+type MultinomialTrainer struct {
+	reference float64
+	lambda    float64
+	maxIt     int
+}
+
+// NewMultinomialTrainer returns a trainer for the response classes relative
+// to the reference class, with an L2 penalty of lambda on the coefficients
+// (0 for none).
+func NewMultinomialTrainer(reference, lambda float64) *MultinomialTrainer {
+	return &MultinomialTrainer{
+		reference: reference,
+		lambda:    lambda,
+		maxIt:     100,
+	}
+}
+
+// MultinomialSummary summarizes a multinomial logistic regression.
+type MultinomialSummary struct {
+	betas        []float64
+	stdErrs      []float64
+	probs        [][]float64
+	classes      []float64
+	residuals    []float64
+	response     []float64
+	levels       []float64
+	deviance     float64
+	nullDeviance float64
+	iterations   int
+	converged    bool
+	data         *DataFrame
+}
+
+func (m *MultinomialSummary) Data() *DataFrame    { return m.data }
+func (m *MultinomialSummary) Response() []float64 { return m.response }
+
+// Coefficients returns the coefficients of each non-reference class in turn,
+// intercept first.
+func (m *MultinomialSummary) Coefficients() []float64 { return m.betas }
+
+// Yhat returns the most probable class of each row.
+func (m *MultinomialSummary) Yhat() []float64 { return m.classes }
+
+// Residuals returns 1 - P(y_i | x_i), the probability not given to the
+// observed class.
+func (m *MultinomialSummary) Residuals() []float64 { return m.residuals }
+
+// SumOfSquares returns the sum of squared residuals.
+func (m *MultinomialSummary) SumOfSquares() float64 {
+	return sum(prod(m.residuals, m.residuals))
+}
+
+// StdErrors returns the standard errors of the coefficients, from the inverse
+// of the (penalized) information matrix.
+func (m *MultinomialSummary) StdErrors() []float64 { return m.stdErrs }
+
+// Probabilities returns the n x K fitted class probabilities, with the classes
+// in the order of Levels.
+func (m *MultinomialSummary) Probabilities() *DataFrame {
+	return NewDataFrame(m.probs)
+}
+
+// Levels returns the classes, in increasing order.
+func (m *MultinomialSummary) Levels() []float64 { return m.levels }
+
+// Deviance returns -2 times the log-likelihood.
+func (m *MultinomialSummary) Deviance() float64 { return m.deviance }
+
+// NullDeviance returns the deviance of the intercept-only model.
+func (m *MultinomialSummary) NullDeviance() float64 { return m.nullDeviance }
+
+// AIC returns the deviance plus twice the number of coefficients.
+func (m *MultinomialSummary) AIC() float64 {
+	return m.deviance + 2*float64(len(m.betas))
+}
+
+// Accuracy returns the proportion of rows whose most probable class is the
+// observed one.
+func (m *MultinomialSummary) Accuracy() float64 {
+	right := 0.0
+	for i, c := range m.classes {
+		if c == m.response[i] {
+			right++
+		}
+	}
+	return right / float64(len(m.classes))
+}
+
+// Iterations returns the number of Newton-Raphson iterations run.
+func (m *MultinomialSummary) Iterations() int { return m.iterations }
+
+// Converged reports whether the Newton-Raphson steps met the tolerance before
+// the iteration limit. If not, the coefficients are the last iterate.
+func (m *MultinomialSummary) Converged() bool { return m.converged }
+
+func (m *MultinomialSummary) String() string {
+	return fmt.Sprintf(`
+		Levels: %v
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+
+		Null deviance: %v
+		Residual deviance: %v
+		AIC: %v
+		Accuracy: %v
+		Iterations: %d (converged: %v)`,
+		m.levels,
+		roundAll(m.betas),
+		roundAll(m.stdErrs),
+		round(m.nullDeviance, 3),
+		round(m.deviance, 3),
+		round(m.AIC(), 3),
+		round(m.Accuracy(), 4),
+		m.iterations,
+		m.converged,
+	)
+}
+
+// Train fits the model. The response holds the class of each row; there must
+// be at least two classes, one of them the reference.
+func (t *MultinomialTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
+	n := df.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+
+	levels := uniqueSorted(y)
+	if len(levels) < 2 {
+		return nil, nil, fmt.Errorf("need at least two classes")
+	}
+	ref := sort.SearchFloat64s(levels, t.reference)
+	if ref == len(levels) || levels[ref] != t.reference {
+		return nil, nil, fmt.Errorf("reference %v is not a class", t.reference)
+	}
+
+	var (
+		data  = withIntercept(df)
+		x     = data.Data()
+		k     = len(levels)
+		p     = data.Cols()
+		q     = (k - 1) * p
+		class = make([]int, n)
+	)
+	for i, v := range y {
+		class[i] = sort.SearchFloat64s(levels, v)
+	}
+
+	// the free classes, in order, skipping the reference
+	free := make([]int, 0, k-1)
+	for c := 0; c < k; c++ {
+		if c != ref {
+			free = append(free, c)
+		}
+	}
+
+	// theta holds the coefficients of the free classes, stacked
+	theta := make([]float64, q)
+	betas := func(theta []float64) [][]float64 {
+		b := make([][]float64, k)
+		b[ref] = rep(0.0, p)
+		for j, c := range free {
+			b[c] = theta[j*p : (j+1)*p]
+		}
+		return b
+	}
+
+	// penalized log-likelihood; the intercept is the first column of x
+	objective := func(theta []float64) float64 {
+		b := betas(theta)
+		ll := 0.0
+		for i := 0; i < n; i++ {
+			ll += math.Log(softmax(linearScores(x, i, b))[class[i]])
+		}
+		for j, v := range theta {
+			if j%p != 0 {
+				ll -= t.lambda / 2 * v * v
+			}
+		}
+		return ll
+	}
+
+	var (
+		info      *mat64.Dense
+		iters     int
+		converged bool
+		obj       = objective(theta)
+	)
+	for ; iters < t.maxIt; iters++ {
+		// gradient and information matrix
+		grad := make([]float64, q)
+		info = mat64.NewDense(q, q, nil)
+		b := betas(theta)
+		for i := 0; i < n; i++ {
+			row := mat64.Row(nil, i, x)
+			pr := softmax(linearScores(x, i, b))
+			for a, ca := range free {
+				resid := -pr[ca]
+				if class[i] == ca {
+					resid++
+				}
+				for u := 0; u < p; u++ {
+					grad[a*p+u] += row[u] * resid
+				}
+				for c, cb := range free[a:] {
+					w := -pr[ca] * pr[cb]
+					if c == 0 {
+						w += pr[ca]
+					}
+					for u := 0; u < p; u++ {
+						for v := 0; v < p; v++ {
+							info.Set(a*p+u, (a+c)*p+v, info.At(a*p+u, (a+c)*p+v)+w*row[u]*row[v])
+						}
+					}
+				}
+			}
+		}
+		for a := 0; a < q; a++ {
+			for c := 0; c < a; c++ {
+				info.Set(a, c, info.At(c, a))
+			}
+			if a%p != 0 {
+				grad[a] -= t.lambda * theta[a]
+				info.Set(a, a, info.At(a, a)+t.lambda)
+			}
+		}
+
+		step := &mat64.Dense{}
+		if err := step.Solve(info, mat64.NewDense(q, 1, grad)); err != nil {
+			return nil, nil, err
+		}
+		delta := mat64.Col(nil, 0, step)
+
+		// step-halving on the penalized log-likelihood
+		next := make([]float64, q)
+		var objNext float64
+		for h := 0; h < maxHalvings; h++ {
+			for j := range next {
+				next[j] = theta[j] + delta[j]
+			}
+			objNext = objective(next)
+			if objNext >= obj-1e-10*(math.Abs(obj)+0.1) {
+				break
+			}
+			delta = multSlice(delta, 0.5)
+		}
+
+		theta, obj = next, objNext
+		if math.Sqrt(dot(delta, delta)) <= DefaultTolerance {
+			iters++
+			converged = true
+			break
+		}
+	}
+
+	cov := &mat64.Dense{}
+	if err := cov.Inverse(info); err != nil {
+		return nil, nil, err
+	}
+
+	final := betas(theta)
+	s := &MultinomialSummary{
+		stdErrs:    make([]float64, 0, q),
+		probs:      make([][]float64, n),
+		classes:    make([]float64, n),
+		residuals:  make([]float64, n),
+		response:   append([]float64(nil), y...),
+		levels:     levels,
+		iterations: iters,
+		converged:  converged,
+		data:       data,
+	}
+	for a, c := range free {
+		s.betas = append(s.betas, final[c]...)
+		for u := 0; u < p; u++ {
+			s.stdErrs = append(s.stdErrs, math.Sqrt(cov.At(a*p+u, a*p+u)))
+		}
+	}
+
+	model := &Multinomial{
+		levels:    levels,
+		reference: ref,
+		betas:     final,
+	}
+	counts := make([]float64, k)
+	for i := 0; i < n; i++ {
+		s.probs[i] = model.Probabilities(df.GetRow(i))
+		s.classes[i] = levels[argmax(s.probs[i])]
+		s.residuals[i] = 1 - s.probs[i][class[i]]
+		s.deviance -= 2 * math.Log(s.probs[i][class[i]])
+		counts[class[i]]++
+	}
+	for _, c := range counts {
+		s.nullDeviance -= 2 * c * math.Log(c/float64(n))
+	}
+
+	return model, s, nil
+}
+
+// linearScores returns the linear predictor of every class for row i.
+func linearScores(x *mat64.Dense, i int, betas [][]float64) []float64 {
+	row := mat64.Row(nil, i, x)
+	eta := make([]float64, len(betas))
+	for c, b := range betas {
+		eta[c] = dot(row, b)
+	}
+	return eta
+}
+
+// softmax returns exp(eta_k) / sum_j exp(eta_j), shifting by the largest
+// eta to avoid overflow.
+func softmax(eta []float64) []float64 {
+	m := max(eta)
+	out := make([]float64, len(eta))
+	for k, v := range eta {
+		out[k] = math.Exp(v - m)
+	}
+	return multSlice(out, 1/sum(out))
+}
+
+// argmax returns the index of the largest value, the first if tied.
+func argmax(x []float64) int {
+	best := 0
+	for i, v := range x {
+		if v > x[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// uniqueSorted returns the distinct values of x in increasing order.
+func uniqueSorted(x []float64) []float64 {
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	var out []float64
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+var (
+	mnX = [][]float64{
+		{1.2, 0.3}, {0.8, 1.5}, {2.9, 0.1}, {3.1, 1.9}, {0.2, 2.8}, {1.7, 2.2},
+		{2.5, 2.7}, {0.4, 0.9}, {3.6, 0.6}, {1.1, 3.3}, {2.2, 1.1}, {0.6, 1.8},
+		{3.3, 2.4}, {1.9, 0.4}, {0.9, 2.5}, {2.7, 3.0}, {1.4, 1.2}, {3.0, 1.4},
+	}
+	mnY = []float64{0, 1, 0, 2, 1, 1, 2, 0, 2, 1, 0, 1, 2, 0, 2, 2, 1, 0}
+)
+
+// with two classes, multinomial regression is logistic regression
+func TestMultinomialBinary(t *testing.T) {
+	y := make([]float64, len(mnY))
+	for i, v := range mnY {
+		if v == 1 {
+			y[i] = 1
+		}
+	}
+	df := NewDataFrame(mnX)
+
+	_, logit, err := NewGlmTrainer(NewGLMConfig(Binomial, 50, 1e-10)).Train(df, y)
+	assert.Equal(t, nil, err)
+	model, summary, err := NewMultinomialTrainer(0, 0).Train(df, y)
+	assert.Equal(t, nil, err)
+
+	assertNear(t, logit.Coefficients(), summary.Coefficients(), 1e-6)
+	assertNear(t, logit.(*GLMSummary).StdErrors(), summary.(*MultinomialSummary).StdErrors(), 1e-6)
+	assertNear(t, []float64{logit.(*GLMSummary).Deviance()}, []float64{summary.(*MultinomialSummary).Deviance()}, 1e-6)
+	assertNear(t, []float64{logit.Yhat()[3]}, []float64{model.(*Multinomial).Probabilities(mnX[3])[1]}, 1e-6)
+}
+
+func TestMultinomial(t *testing.T) {
+	df := NewDataFrame(mnX)
+	model, summary, err := NewMultinomialTrainer(1, 0).Train(df, mnY)
+	assert.Equal(t, nil, err)
+
+	m := model.(*Multinomial)
+	s := summary.(*MultinomialSummary)
+	assert.Equal(t, []float64{0, 1, 2}, m.Levels())
+	assert.Equal(t, 1.0, m.Reference())
+	assert.Equal(t, []float64{0, 0, 0}, m.Coefficients(1))
+	assert.Equal(t, 6, len(s.Coefficients()))
+
+	// score equations: for each class, sum (y_ik - p_ik) x_i = 0
+	probs := s.Probabilities()
+	for k, level := range m.Levels() {
+		score := make([]float64, 3)
+		for i, row := range mnX {
+			r := -probs.GetRow(i)[k]
+			if mnY[i] == level {
+				r++
+			}
+			score[0] += r
+			score[1] += r * row[0]
+			score[2] += r * row[1]
+		}
+		assertNear(t, []float64{0, 0, 0}, score, 1e-6)
+	}
+
+	p := m.Probabilities(mnX[0])
+	assertNear(t, []float64{1}, []float64{sum(p)}, 1e-12)
+	assert.Equal(t, m.Levels()[argmax(p)], m.Predict(mnX[0]))
+	assert.Equal(t, true, s.Deviance() < s.NullDeviance())
+	assert.Equal(t, true, s.Converged())
+
+	// a single step does not reach the tolerance
+	short := NewMultinomialTrainer(1, 0)
+	short.maxIt = 1
+	_, partial, err := short.Train(df, mnY)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, partial.(*MultinomialSummary).Converged())
+	assert.Equal(t, 1, partial.(*MultinomialSummary).Iterations())
+
+	// the penalty shrinks the slopes
+	_, ridge, err := NewMultinomialTrainer(1, 5).Train(df, mnY)
+	assert.Equal(t, nil, err)
+	slopes := func(b []float64) float64 {
+		return b[1]*b[1] + b[2]*b[2] + b[4]*b[4] + b[5]*b[5]
+	}
+	assert.Equal(t, true, slopes(ridge.Coefficients()) < slopes(s.Coefficients()))
+
+	_, _, err = NewMultinomialTrainer(3, 0).Train(df, mnY)
+	assert.NotEqual(t, nil, err)
+}
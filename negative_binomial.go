@@ -0,0 +1,185 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// NegativeBinomial returns the negative binomial family with the log link,
+// for overdispersed counts with variance
+//
+// var(y) = mu + mu^2 / theta
+//
+// If theta is not positive it is estimated by maximum likelihood, alternating
+// with the IRLS fit of the coefficients: starting from a Poisson fit, theta is
+// estimated from the fitted means, the coefficients refit with that theta, and
+// so on until both settle (as in MASS's glm.nb).
+func NegativeBinomial(theta float64) Family {
+	f := negativeBinomial(theta, Log)
+	if theta <= 0 {
+		f.estimateTheta = true
+	}
+	return f
+}
+
+func negativeBinomial(theta float64, link Link) Family {
+	return Family{
+		Name: "negative binomial",
+		Link: link,
+		VarianceFn: func(mu float64) float64 {
+			return mu + mu*mu/theta
+		},
+		DevianceFn: func(y, mu float64) float64 {
+			return 2 * (ylogy(y, mu) - (y+theta)*math.Log((y+theta)/(mu+theta)))
+		},
+		LogLikFn: func(y, mu, w, _ float64) float64 {
+			return w * negBinLogLik(y, mu, theta)
+		},
+		InitFn:     poissonInit,
+		Dispersion: 1,
+		theta:      theta,
+		links:      []string{"log", "identity", "sqrt"},
+	}
+}
+
+// log(gamma(theta + y) / (gamma(theta) y!)) + theta log(theta / (theta + mu))
+// + y log(mu / (theta + mu))
+func negBinLogLik(y, mu, theta float64) float64 {
+	a, _ := math.Lgamma(theta + y)
+	b, _ := math.Lgamma(theta)
+	c, _ := math.Lgamma(y + 1)
+	ll := a - b - c + theta*math.Log(theta/(theta+mu))
+	if y > 0 {
+		ll += y * math.Log(mu/(theta+mu))
+	}
+	return ll
+}
+
+// upper bound on the number of alternations between theta and the
+// coefficients
+const maxThetaIterations = 25
+
+// alternateTheta fits a negative binomial GLM with unknown theta. It returns
+// the config with the family at the final theta, the coefficients and the
+// total number of IRLS iterations.
+func alternateTheta(config *GLMConfig, A *mat64.Dense, y, prior, offset []float64) (*GLMConfig, []float64, int, error) {
+	link := config.F.Link
+	fit := *config
+	fit.F = Poisson
+	fit.F.Link = link
+
+	coef, iters, err := irls(&fit, A, y, prior, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	mu := glmMeans(link, A, coef, offset)
+	theta, err := thetaML(y, mu, prior)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	dev := math.Inf(1)
+	for i := 0; i < maxThetaIterations; i++ {
+		fit.F = negativeBinomial(theta, link)
+		fit.F.estimateTheta = true
+
+		var it int
+		coef, it, err = irls(&fit, A, y, prior, offset)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		iters += it
+
+		mu = glmMeans(link, A, coef, offset)
+		thetaNew, err := thetaML(y, mu, prior)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		devNew, _ := glmDeviance(fit.F, y, prior, linearPredictor(A, coef, offset))
+
+		converged := math.Abs(thetaNew-theta) <= config.Tolerance*(theta+1) &&
+			math.Abs(devNew-dev) <= config.Tolerance*(math.Abs(devNew)+0.1)
+		theta, dev = thetaNew, devNew
+		if converged {
+			break
+		}
+	}
+
+	// refit at the final theta, so the coefficients and theta agree
+	fit.F = negativeBinomial(theta, link)
+	fit.F.estimateTheta = true
+	coef, it, err := irls(&fit, A, y, prior, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return &fit, coef, iters + it, nil
+}
+
+// glmMeans returns invLink(X beta + offset).
+func glmMeans(link Link, A *mat64.Dense, beta, offset []float64) []float64 {
+	mu := linearPredictor(A, beta, offset)
+	for i, v := range mu {
+		mu[i] = link.InverseFn(v)
+	}
+	return mu
+}
+
+// thetaML returns the maximum likelihood estimate of theta given the means,
+// by Newton's method on the score
+//
+// sum w (digamma(theta + y) - digamma(theta) + log(theta) + 1
+//   - log(theta + mu) - (y + theta) / (mu + theta))
+//
+// starting from the moment estimate n / sum w (y / mu - 1)^2.
+func thetaML(y, mu, prior []float64) (float64, error) {
+	var n, s float64
+	for i := range y {
+		n += prior[i]
+		s += prior[i] * (y[i]/mu[i] - 1) * (y[i]/mu[i] - 1)
+	}
+	theta := n / s
+
+	for i := 0; i < maxThetaIterations; i++ {
+		var score, info float64
+		for j := range y {
+			t, m, w := theta, mu[j], prior[j]
+			score += w * (digamma(t+y[j]) - digamma(t) + math.Log(t) + 1 - math.Log(t+m) - (y[j]+t)/(m+t))
+			info += w * (-trigamma(t+y[j]) + trigamma(t) - 1/t + 2/(m+t) - (y[j]+t)/((m+t)*(m+t)))
+		}
+		step := score / info
+		theta += step
+		if theta <= 0 || math.IsNaN(theta) {
+			return 0, fmt.Errorf("theta estimate is not positive; the counts may not be overdispersed")
+		}
+		if math.Abs(step) <= 1e-8*theta {
+			break
+		}
+	}
+	return theta, nil
+}
+
+// digamma(x) for x > 0, by the recurrence digamma(x) = digamma(x+1) - 1/x and
+// the asymptotic series for large x
+func digamma(x float64) float64 {
+	r := 0.0
+	for ; x < 6; x++ {
+		r -= 1 / x
+	}
+	f := 1 / (x * x)
+	return r + math.Log(x) - 0.5/x -
+		f*(1.0/12-f*(1.0/120-f*(1.0/252-f*(1.0/240-f*(1.0/132)))))
+}
+
+// trigamma(x) for x > 0, by the recurrence trigamma(x) = trigamma(x+1) + 1/x^2
+// and the asymptotic series for large x
+func trigamma(x float64) float64 {
+	r := 0.0
+	for ; x < 6; x++ {
+		r += 1 / (x * x)
+	}
+	f := 1 / (x * x)
+	return r + 1/x + f/2 +
+		f/x*(1.0/6-f*(1.0/30-f*(1.0/42-f*(1.0/30-f*5.0/66))))
+}
@@ -0,0 +1,78 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestQuasiPoisson(t *testing.T) {
+	var (
+		counts = []float64{18, 17, 15, 20, 10, 20, 25, 13, 12}
+		dobson = NewDataFrame([][]float64{
+			{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 1, 0, 0},
+			{0, 0, 1, 0}, {1, 0, 1, 0}, {0, 1, 1, 0},
+			{0, 0, 0, 1}, {1, 0, 0, 1}, {0, 1, 0, 1},
+		})
+	)
+
+	_, pois, err := NewGlmTrainer(NewGLMConfig(Poisson, 25, 1e-8)).Train(dobson, counts)
+	assert.Equal(t, nil, err)
+	_, quasi, err := NewGlmTrainer(NewGLMConfig(QuasiPoisson, 25, 1e-8)).Train(dobson, counts)
+	assert.Equal(t, nil, err)
+
+	// same coefficients, standard errors scaled by the root of the dispersion
+	q := quasi.(*GLMSummary)
+	assert.Equal(t, 1.2933, round(q.Dispersion(), 4))
+	assertNear(t, pois.Coefficients(), q.Coefficients(), 1e-8)
+	assertNear(t, multSlice(pois.(*GLMSummary).StdErrors(), math.Sqrt(q.Dispersion())), q.StdErrors(), 1e-8)
+	assert.Equal(t, true, math.IsNaN(q.AIC()))
+}
+
+func TestNegativeBinomial(t *testing.T) {
+	var (
+		x      = [][]float64{{0}, {0.5}, {1}, {1.5}, {2}, {2.5}, {3}, {3.5}, {4}, {4.5}, {5}, {5.5}}
+		counts = []float64{0, 3, 1, 7, 2, 12, 4, 20, 9, 3, 31, 14}
+		df     = NewDataFrame(x)
+	)
+
+	_, summary, err := NewGlmTrainer(NewGLMConfig(NegativeBinomial(0), 50, 1e-10)).Train(df, counts)
+	assert.Equal(t, nil, err)
+	s := summary.(*GLMSummary)
+	theta := s.Theta()
+	assert.Equal(t, true, theta > 0)
+	assert.Equal(t, 1.0, s.Dispersion())
+
+	// the coefficients solve sum (y - mu) / (1 + mu / theta) x = 0, and theta
+	// maximizes the likelihood at the fitted means
+	mu := s.Yhat()
+	score := []float64{0, 0}
+	for i, y := range counts {
+		r := (y - mu[i]) / (1 + mu[i]/theta)
+		score[0] += r
+		score[1] += r * x[i][0]
+	}
+	assertNear(t, []float64{0, 0}, score, 1e-6)
+
+	ll := func(theta float64) float64 {
+		l := 0.0
+		for i, y := range counts {
+			l += negBinLogLik(y, mu[i], theta)
+		}
+		return l
+	}
+	assert.Equal(t, true, ll(theta) > ll(theta*1.01) && ll(theta) > ll(theta*0.99))
+
+	// a fixed theta is taken as given
+	_, fixed, err := NewGlmTrainer(NewGLMConfig(NegativeBinomial(theta), 50, 1e-10)).Train(df, counts)
+	assert.Equal(t, nil, err)
+	assertNear(t, s.Coefficients(), fixed.Coefficients(), 1e-6)
+	assert.Equal(t, round(s.AIC()-2, 6), round(fixed.(*GLMSummary).AIC(), 6))
+}
+
+func TestDigamma(t *testing.T) {
+	// -euler's constant and pi^2 / 6
+	assertNear(t, []float64{-0.5772156649, 1.6449340668}, []float64{digamma(1), trigamma(1)}, 1e-9)
+	assertNear(t, []float64{digamma(2.5) + 1/2.5}, []float64{digamma(3.5)}, 1e-12)
+}
@@ -0,0 +1,364 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Ordinal logistic regression (the proportional odds model) models an
+// ordered response with K levels through the cumulative probabilities
+//
+// logit P(y <= k | x) = zeta_k - x beta,		k = 1 .. K-1
+//
+// with increasing cutpoints zeta_k and one set of slopes shared by every
+// level, so that a positive coefficient moves the response towards the
+// higher levels. The probability of level k is
+// P(y <= k | x) - P(y <= k-1 | x). With K = 2 it is the logistic regression
+// of the higher level, with intercept -zeta_1.
+type Ordinal struct {
+	levels    []float64
+	cutpoints []float64
+	betas     []float64
+}
+
+// Probabilities returns the probability of each level, in the order of
+// Levels.
+func (o *Ordinal) Probabilities(x []float64) []float64 {
+	eta := sum(prod(x, o.betas))
+	probs := make([]float64, len(o.levels))
+	for k := range probs {
+		probs[k] = o.levelProb(k, eta)
+	}
+	return probs
+}
+
+// levelProb returns P(y = level k) at the linear predictor eta.
+func (o *Ordinal) levelProb(k int, eta float64) float64 {
+	upper, lower := math.Inf(1), math.Inf(-1)
+	if k < len(o.cutpoints) {
+		upper = o.cutpoints[k] - eta
+	}
+	if k > 0 {
+		lower = o.cutpoints[k-1] - eta
+	}
+	return intervalProb(lower, upper)
+}
+
+// intervalProb returns F(upper) - F(lower) for the logistic cdf F, in the
+// tail where it is accurate.
+func intervalProb(lower, upper float64) float64 {
+	if lower > 0 {
+		return logistic(-lower) - logistic(-upper)
+	}
+	return logistic(upper) - logistic(lower)
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Classify returns the most probable level.
+func (o *Ordinal) Classify(x []float64) float64 {
+	return o.levels[argmax(o.Probabilities(x))]
+}
+
+// Predict returns the most probable level.
+func (o *Ordinal) Predict(x []float64) float64 {
+	return o.Classify(x)
+}
+
+// Levels returns the levels of the response, in increasing order.
+func (o *Ordinal) Levels() []float64 { return o.levels }
+
+// Cutpoints returns the K-1 cutpoints zeta_k.
+func (o *Ordinal) Cutpoints() []float64 { return o.cutpoints }
+
+type ordinalTrainer struct {
+	maxIt int
+}
+
+// NewOrdinalTrainer returns a Trainer for the proportional odds model. The
+// response holds the level of each row; the levels are ordered by value.
+func NewOrdinalTrainer() Trainer {
+	return &ordinalTrainer{
+		maxIt: 100,
+	}
+}
+
+// OrdinalSummary summarizes a proportional odds model.
+type OrdinalSummary struct {
+	cutpoints  []float64
+	betas      []float64
+	cutErrs    []float64
+	stdErrs    []float64
+	probs      [][]float64
+	classes    []float64
+	residuals  []float64
+	response   []float64
+	levels     []float64
+	deviance   float64
+	iterations int
+	converged  bool
+	data       *DataFrame
+}
+
+// Data returns the predictors with a leading column of ones, as for the other
+// fits. The cutpoints take the place of the coefficient of that column, so
+// Coefficients has one entry fewer.
+func (o *OrdinalSummary) Data() *DataFrame    { return o.data }
+func (o *OrdinalSummary) Response() []float64 { return o.response }
+
+// Coefficients returns the slopes beta.
+func (o *OrdinalSummary) Coefficients() []float64 { return o.betas }
+
+// Cutpoints returns the K-1 cutpoints zeta_k.
+func (o *OrdinalSummary) Cutpoints() []float64 { return o.cutpoints }
+
+// StdErrors returns the standard errors of the slopes.
+func (o *OrdinalSummary) StdErrors() []float64 { return o.stdErrs }
+
+// CutpointStdErrors returns the standard errors of the cutpoints.
+func (o *OrdinalSummary) CutpointStdErrors() []float64 { return o.cutErrs }
+
+// Yhat returns the most probable level of each row.
+func (o *OrdinalSummary) Yhat() []float64 { return o.classes }
+
+// Residuals returns 1 - P(y_i | x_i), the probability not given to the
+// observed level.
+func (o *OrdinalSummary) Residuals() []float64 { return o.residuals }
+
+// SumOfSquares returns the sum of squared residuals.
+func (o *OrdinalSummary) SumOfSquares() float64 {
+	return sum(prod(o.residuals, o.residuals))
+}
+
+// Probabilities returns the n x K fitted level probabilities.
+func (o *OrdinalSummary) Probabilities() *DataFrame {
+	return NewDataFrame(o.probs)
+}
+
+// Levels returns the levels of the response, in increasing order.
+func (o *OrdinalSummary) Levels() []float64 { return o.levels }
+
+// Deviance returns -2 times the log-likelihood.
+func (o *OrdinalSummary) Deviance() float64 { return o.deviance }
+
+// AIC returns the deviance plus twice the number of cutpoints and slopes.
+func (o *OrdinalSummary) AIC() float64 {
+	return o.deviance + 2*float64(len(o.cutpoints)+len(o.betas))
+}
+
+// Iterations returns the number of Newton-Raphson iterations run.
+func (o *OrdinalSummary) Iterations() int { return o.iterations }
+
+// Converged reports whether the Newton-Raphson steps met the tolerance before
+// the iteration limit. If not, the estimates are the last iterate.
+func (o *OrdinalSummary) Converged() bool { return o.converged }
+
+func (o *OrdinalSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+		Cutpoints:
+		%v
+		Std. Errors:
+		%v
+
+		Residual deviance: %v
+		AIC: %v
+		Iterations: %d (converged: %v)`,
+		roundAll(o.betas),
+		roundAll(o.stdErrs),
+		roundAll(o.cutpoints),
+		roundAll(o.cutErrs),
+		round(o.deviance, 3),
+		round(o.AIC(), 3),
+		o.iterations,
+		o.converged,
+	)
+}
+
+// Train fits the model by Newton-Raphson on theta = (zeta, beta), starting
+// from the cutpoints of the marginal distribution of y and beta = 0. For a
+// row in level k, with upper = zeta_k - x beta and lower = zeta_k-1 - x beta,
+//
+// l = log(F(upper) - F(lower))
+//
+// and both are linear in theta, upper = c_u theta and lower = c_l theta, so
+//
+// dl/dtheta = (f(upper) c_u - f(lower) c_l) / P
+// d2l/dtheta2 = (f'(upper) c_u c_u' - f'(lower) c_l c_l') / P - dl dl'
+//
+// where F is the logistic cdf, f = F(1 - F) and f' = f(1 - 2F).
+func (t *ordinalTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
+	n, p := df.Rows(), df.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+
+	levels := uniqueSorted(y)
+	k := len(levels)
+	if k < 2 {
+		return nil, nil, fmt.Errorf("need at least two levels")
+	}
+
+	class := make([]int, n)
+	counts := make([]float64, k)
+	for i, v := range y {
+		class[i] = sort.SearchFloat64s(levels, v)
+		counts[class[i]]++
+	}
+
+	// theta = (zeta_1 .. zeta_K-1, beta)
+	q := k - 1 + p
+	theta := make([]float64, q)
+	cum := 0.0
+	for j := 0; j < k-1; j++ {
+		cum += counts[j]
+		theta[j] = math.Log(cum / (float64(n) - cum))
+	}
+
+	model := func(theta []float64) *Ordinal {
+		return &Ordinal{
+			levels:    levels,
+			cutpoints: theta[:k-1],
+			betas:     theta[k-1:],
+		}
+	}
+	loglik := func(theta []float64) float64 {
+		for j := 1; j < k-1; j++ {
+			if theta[j] <= theta[j-1] {
+				return math.Inf(-1)
+			}
+		}
+		m := model(theta)
+		ll := 0.0
+		for i := 0; i < n; i++ {
+			ll += math.Log(m.levelProb(class[i], sum(prod(df.GetRow(i), m.betas))))
+		}
+		return ll
+	}
+
+	var (
+		info      *mat64.Dense
+		iters     int
+		converged bool
+		ll        = loglik(theta)
+	)
+	for ; iters < t.maxIt; iters++ {
+		grad := make([]float64, q)
+		info = mat64.NewDense(q, q, nil)
+		m := model(theta)
+		for i := 0; i < n; i++ {
+			x := df.GetRow(i)
+			eta := sum(prod(x, m.betas))
+			c := class[i]
+
+			// c_u and c_l, and f, f' at the bounds; 0 at infinite bounds
+			cu, cl := make([]float64, q), make([]float64, q)
+			var fu, fl, du, dl float64
+			if c < k-1 {
+				cu[c] = 1
+				for j, v := range x {
+					cu[k-1+j] = -v
+				}
+				F := logistic(m.cutpoints[c] - eta)
+				fu, du = F*(1-F), F*(1-F)*(1-2*F)
+			}
+			if c > 0 {
+				cl[c-1] = 1
+				for j, v := range x {
+					cl[k-1+j] = -v
+				}
+				F := logistic(m.cutpoints[c-1] - eta)
+				fl, dl = F*(1-F), F*(1-F)*(1-2*F)
+			}
+			P := m.levelProb(c, eta)
+
+			g := make([]float64, q)
+			for a := range g {
+				g[a] = (fu*cu[a] - fl*cl[a]) / P
+				grad[a] += g[a]
+			}
+			for a := 0; a < q; a++ {
+				for b := a; b < q; b++ {
+					h := (du*cu[a]*cu[b]-dl*cl[a]*cl[b])/P - g[a]*g[b]
+					info.Set(a, b, info.At(a, b)-h)
+				}
+			}
+		}
+		for a := 0; a < q; a++ {
+			for b := 0; b < a; b++ {
+				info.Set(a, b, info.At(b, a))
+			}
+		}
+
+		step := &mat64.Dense{}
+		if err := step.Solve(info, mat64.NewDense(q, 1, grad)); err != nil {
+			return nil, nil, err
+		}
+		delta := mat64.Col(nil, 0, step)
+
+		// step-halving, which also keeps the cutpoints in order
+		next := make([]float64, q)
+		var llNext float64
+		for h := 0; h < maxHalvings; h++ {
+			for j := range next {
+				next[j] = theta[j] + delta[j]
+			}
+			llNext = loglik(next)
+			if llNext >= ll-1e-10*(math.Abs(ll)+0.1) {
+				break
+			}
+			delta = multSlice(delta, 0.5)
+		}
+		if math.IsInf(llNext, -1) {
+			return nil, nil, fmt.Errorf("no step keeps the cutpoints in order")
+		}
+
+		theta, ll = next, llNext
+		if math.Sqrt(dot(delta, delta)) <= DefaultTolerance {
+			iters++
+			converged = true
+			break
+		}
+	}
+
+	cov := &mat64.Dense{}
+	if err := cov.Inverse(info); err != nil {
+		return nil, nil, err
+	}
+	se := make([]float64, q)
+	for a := range se {
+		se[a] = math.Sqrt(cov.At(a, a))
+	}
+
+	m := model(theta)
+	s := &OrdinalSummary{
+		cutpoints:  m.cutpoints,
+		betas:      m.betas,
+		cutErrs:    se[:k-1],
+		stdErrs:    se[k-1:],
+		probs:      make([][]float64, n),
+		classes:    make([]float64, n),
+		residuals:  make([]float64, n),
+		response:   append([]float64(nil), y...),
+		levels:     levels,
+		deviance:   -2 * ll,
+		iterations: iters,
+		converged:  converged,
+		data:       withIntercept(df),
+	}
+	for i := 0; i < n; i++ {
+		s.probs[i] = m.Probabilities(df.GetRow(i))
+		s.classes[i] = levels[argmax(s.probs[i])]
+		s.residuals[i] = 1 - s.probs[i][class[i]]
+	}
+
+	return m, s, nil
+}
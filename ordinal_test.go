@@ -0,0 +1,132 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestOrdinal(t *testing.T) {
+	var (
+		x = [][]float64{
+			{0.1, 1}, {0.4, 0}, {0.9, 1}, {1.3, 0}, {1.5, 1}, {1.8, 0}, {2.2, 1}, {2.4, 0},
+			{2.9, 1}, {3.1, 0}, {3.5, 1}, {3.8, 0}, {4.2, 1}, {4.4, 0}, {4.9, 1}, {5.3, 0},
+		}
+		ratings = []float64{1, 1, 2, 1, 3, 2, 2, 4, 3, 3, 5, 4, 4, 5, 5, 3}
+		df      = NewDataFrame(x)
+	)
+
+	model, summary, err := NewOrdinalTrainer().Train(df, ratings)
+	assert.Equal(t, nil, err)
+	o := model.(*Ordinal)
+	s := summary.(*OrdinalSummary)
+	assert.Equal(t, 4, len(s.Cutpoints()))
+	assert.Equal(t, 2, len(s.Coefficients()))
+	assert.Equal(t, true, s.Coefficients()[0] > 0)
+
+	// the fit is a maximum: moving any parameter raises the deviance
+	deviance := func(m *Ordinal) float64 {
+		d := 0.0
+		for i, row := range x {
+			d -= 2 * math.Log(m.Probabilities(row)[int(ratings[i])-1])
+		}
+		return d
+	}
+	assertNear(t, []float64{s.Deviance()}, []float64{deviance(o)}, 1e-8)
+	params := append(append([]float64(nil), o.Cutpoints()...), s.Coefficients()...)
+	for j := range params {
+		for _, h := range []float64{-1e-3, 1e-3} {
+			moved := append([]float64(nil), params...)
+			moved[j] += h
+			m := &Ordinal{levels: o.Levels(), cutpoints: moved[:4], betas: moved[4:]}
+			assert.Equal(t, true, deviance(m) > s.Deviance())
+		}
+	}
+
+	// the standard errors come from the inverse of the observed information,
+	// here half the numerical Hessian of the deviance
+	var (
+		q    = len(params)
+		info = mat64.NewDense(q, q, nil)
+		h    = 1e-4
+	)
+	at := func(d ...float64) float64 {
+		moved := append([]float64(nil), params...)
+		for j := range moved {
+			moved[j] += d[j]
+		}
+		return deviance(&Ordinal{levels: o.Levels(), cutpoints: moved[:4], betas: moved[4:]})
+	}
+	for a := 0; a < q; a++ {
+		for b := 0; b < q; b++ {
+			pp, pm, mp, mm := make([]float64, q), make([]float64, q), make([]float64, q), make([]float64, q)
+			pp[a] += h
+			pp[b] += h
+			pm[a] += h
+			pm[b] -= h
+			mp[a] -= h
+			mp[b] += h
+			mm[a] -= h
+			mm[b] -= h
+			info.Set(a, b, (at(pp...)-at(pm...)-at(mp...)+at(mm...))/(8*h*h))
+		}
+	}
+	cov := &mat64.Dense{}
+	assert.Equal(t, nil, cov.Inverse(info))
+	se := make([]float64, q)
+	for a := range se {
+		se[a] = math.Sqrt(cov.At(a, a))
+	}
+	assertNear(t, se, append(append([]float64(nil), s.CutpointStdErrors()...), s.StdErrors()...), 1e-3)
+
+	p := o.Probabilities(x[0])
+	assertNear(t, []float64{1}, []float64{sum(p)}, 1e-12)
+	assert.Equal(t, o.Levels()[argmax(p)], model.Predict(x[0]))
+	assert.Equal(t, 5, s.Probabilities().Cols())
+}
+
+// with a predictor balanced across the levels the slope is 0, and the
+// cutpoints are the logits of the cumulative proportions
+func TestOrdinalBalanced(t *testing.T) {
+	var (
+		x       = NewDataFrame([][]float64{{-1}, {1}, {-1}, {1}, {-1}, {1}, {-1}, {1}, {-1}, {1}})
+		ratings = []float64{1, 1, 2, 2, 2, 2, 3, 3, 3, 3}
+	)
+	_, summary, err := NewOrdinalTrainer().Train(x, ratings)
+	assert.Equal(t, nil, err)
+	s := summary.(*OrdinalSummary)
+	assertNear(t, []float64{0}, s.Coefficients(), 1e-8)
+	assertNear(t, []float64{math.Log(0.2 / 0.8), math.Log(0.6 / 0.4)}, s.Cutpoints(), 1e-8)
+
+	_, _, err = NewOrdinalTrainer().Train(x, rep(1, 10))
+	assert.NotEqual(t, nil, err)
+}
+
+// with two levels the model is a logit for the higher one, with intercept
+// -zeta_1
+func TestOrdinalTwoLevels(t *testing.T) {
+	var (
+		x      = NewDataFrame([][]float64{{0.1}, {0.4}, {0.9}, {1.3}, {1.5}, {1.8}, {2.2}, {2.4}, {2.9}, {3.1}})
+		passed = []float64{0, 0, 1, 0, 0, 1, 0, 1, 1, 1}
+	)
+	_, summary, err := NewOrdinalTrainer().Train(x, passed)
+	assert.Equal(t, nil, err)
+	s := summary.(*OrdinalSummary)
+
+	_, logit, err := NewGlmTrainer(NewGLMConfig(Binomial, 50, 1e-10)).Train(x, passed)
+	assert.Equal(t, nil, err)
+	b := logit.Coefficients()
+	assertNear(t, []float64{-b[0]}, s.Cutpoints(), 1e-6)
+	assertNear(t, b[1:], s.Coefficients(), 1e-6)
+	assert.Equal(t, s.Data().Cols(), len(s.Coefficients())+1)
+	assert.Equal(t, true, s.Converged())
+
+	// a single step does not reach the tolerance
+	short := NewOrdinalTrainer().(*ordinalTrainer)
+	short.maxIt = 1
+	_, partial, err := short.Train(x, passed)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, partial.(*OrdinalSummary).Converged())
+}
@@ -1,93 +1,180 @@
 package glasso
 
-/*
 import (
+	"fmt"
+
+	"github.com/gonum/matrix"
 	"github.com/gonum/matrix/mat64"
 )
 
+// Principal component regression forms the derived input columns z_m = X v_m,
+// where v_m are the principal directions of the standardized X, and then
+// regresses y on z_1, z_2, . . . , z_M for some M <= p. Since the z_m
+// are orthogonal, this regression is just a sum of univariate regressions:
+//
+// y_hat = y_bar + sum \theta_m z_m
+// where \theta_m = <z_m, y> / <z_m, z_m>
+//
+// Mapping back through the directions gives coefficients on the predictors,
+// \beta = sum \theta_m v_m, so the model predicts from raw rows.
 type PCR struct {
-	x *DataFrame
-	z *DataFrame
-	m int
+	betas []float64
 }
 
-func NewPCR(x *DataFrame, m int) *PCR {
-	z := mat64.DenseCopyOf(x.data)
-	r, c := z.Dims()
+func (p *PCR) Predict(x []float64) float64 {
+	return p.betas[0] + sum(prod(x, p.betas[1:]))
+}
 
-	// need to standardize x for best results
-	x.Standardize()
+type pcrTrainer struct {
+	m         int
+	threshold float64
+}
 
-	return &PCR{
-		x: x,
-		z: &DataFrame{z, r, c, nil},
+// NewPCRTrainer returns a Trainer regressing on the first m principal
+// components.
+func NewPCRTrainer(m int) Trainer {
+	return &pcrTrainer{
 		m: m,
 	}
 }
 
-/*
-func (p *PCR) GetPrinComps(k float64) *mat64.Dense {
-	epsilon := math.Pow(2, -52.0)
-	small := math.Pow(2, -966.0)
+// NewPCRVarianceTrainer returns a Trainer regressing on the fewest principal
+// components that together explain at least the threshold proportion of the
+// variance of X.
+func NewPCRVarianceTrainer(threshold float64) Trainer {
+	return &pcrTrainer{
+		threshold: threshold,
+	}
+}
+
+// PCRSummary is the summary of a principal component regression.
+type PCRSummary struct {
+	OlsSummary
+	explained []float64
+	m         int
+}
 
-	svd := mat64.SVD(mat64.DenseCopyOf(p.x.data), epsilon, small, false, true)
+// ExplainedVariance returns the proportion of the variance of the
+// standardized X explained by each principal component.
+func (p PCRSummary) ExplainedVariance() []float64 { return p.explained }
 
-	// Sigma is a square + diagonal matrix
-	eigenvalues := svd.Sigma
-	l := len(S)
+// Components returns the number of components used in the regression.
+func (p PCRSummary) Components() int { return p.m }
 
-	sum := 0.0
-	for i := 0; i < r; i++ {
-		sum += eigenvalues[i]
+func (p *pcrTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n, c := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
 	}
-	for i := 0; i < r; i++ {
-		eigenvalues[i] /= sum
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	// need to standardize x for best results
+	cols, means, sds := scaleCols(x)
+	d, v, err := principalComponents(cols)
+	if err != nil {
+		return nil, nil, err
 	}
-	varexplained := 0.0
+	explained := explainedVariance(d)
 
-	r, c := p.x.rows, p.x.cols
-	if k > c {
-		log.Println("cannot have k (cutoff) > # columns")
-		return nil
+	m := p.m
+	if p.threshold > 0 {
+		m = componentsFor(explained, p.threshold)
+	}
+	if m < 1 || m > len(d) {
+		return nil, nil, fmt.Errorf("number of components must be between 1 and %d", len(d))
 	}
 
-	// projection matrix W:
-	W := mat64.NewDense(c, k, nil)
-	// eigenvectors
-	V := svd.V
-	for i := 0; i < k; i++ {
-		var col []float64
-		V.Col(col, i)
-		W.SetCol(i, col)
-		varexplained += eigenvalues[i]
+	// z_m = X v_m, and \theta_m = <z_m, y> / <z_m, z_m>
+	r := subtractMean(y)
+	b := rep(0.0, c)
+	for k := 0; k < m; k++ {
+		vk := mat64.Col(nil, k, v)
+		z := make([]float64, n)
+		for j, col := range cols {
+			for i := range z {
+				z[i] += vk[j] * col[i]
+			}
+		}
+		zz := dot(z, z)
+		if zz == 0 {
+			continue
+		}
+		theta := dot(z, r) / zz
+		for j := range b {
+			b[j] += theta * vk[j]
+		}
 	}
 
-	log.Printf(`Projection matrix created. \%%d of data explained`, varexplained)
+	betas := unscaleBetas(b, means, sds, mean(y))
+	fitted, residuals := linearFit(x, betas, response)
 
-	out := &mat64.Dense{}
-	out.Mul(p.z.data, W)
+	return &PCR{
+		betas: betas,
+	}, PCRSummary{
+		OlsSummary: OlsSummary{
+			betas:     betas,
+			residuals: residuals,
+			fitted:    fitted,
+			response:  response,
+			n:         n,
+			p:         c,
+			data:      withIntercept(x),
+		},
+		explained: explained,
+		m:         m,
+	}, nil
+}
 
-	return out
+// ExplainedVariance returns the proportion of the variance of the
+// standardized columns of the DataFrame explained by each principal component.
+func ExplainedVariance(df *DataFrame) ([]float64, error) {
+	cols, _, _ := scaleCols(df)
+	d, _, err := principalComponents(cols)
+	if err != nil {
+		return nil, err
+	}
+	return explainedVariance(d), nil
 }
 
-// Principal component regression forms the derived input columns zm = Xvm
-// and then regresses y on z1, z2, . . . , zM for some M <= p. Since the zm
-// are orthogonal, this regression is just a sum of univariate regressions:
-//
-// y_hat = y_bar + sum \theta_m z_m
-// where \theta_m = <z_m, y> / <z_m, z_m>
-//
-func (p *PCR) Train(y []float64) error {
-	//ybar := mean(y)
+// principalComponents returns the singular values and right singular vectors
+// (the principal directions, as columns) of the matrix with the given columns.
+// X = UDVt
+func principalComponents(cols [][]float64) ([]float64, *mat64.Dense, error) {
+	n, p := len(cols[0]), len(cols)
+	x := mat64.NewDense(n, p, nil)
+	for j, col := range cols {
+		x.SetCol(j, col)
+	}
 
-	theta := make([]float64, p.m)
+	svd := &mat64.SVD{}
+	if ok := svd.Factorize(x, matrix.SVDThin); !ok {
+		return nil, nil, fmt.Errorf("svd factorization failed")
+	}
+	v := &mat64.Dense{}
+	v.VFromSVD(svd)
+	return svd.Values(nil), v, nil
+}
 
-	for i := 0; i < p.m; i++ {
-		z_m := p.z.data.Col(nil, i)
-		theta[i] = sum(prod(z_m, y)) / sum(prod(z_m, z_m))
+// the variance explained by each component is proportional to d_m^2
+func explainedVariance(d []float64) []float64 {
+	explained := make([]float64, len(d))
+	for i, v := range d {
+		explained[i] = v * v
 	}
+	return multSlice(explained, 1/sum(explained))
+}
 
-	// ...
-	return nil
+// componentsFor returns the fewest components explaining at least threshold of
+// the variance.
+func componentsFor(explained []float64, threshold float64) int {
+	total := 0.0
+	for i, e := range explained {
+		total += e
+		if total >= threshold-1e-12 {
+			return i + 1
+		}
+	}
+	return len(explained)
 }
-*/
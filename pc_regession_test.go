@@ -0,0 +1,35 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestPCR(t *testing.T) {
+	explained, err := ExplainedVariance(NewDataFrame(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(explained))
+	assert.Equal(t, 1.0, round(sum(explained), 6))
+	assert.T(t, explained[0] >= explained[1] && explained[1] >= explained[2])
+
+	// keeping every component is least squares
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, summary, err := NewPCRTrainer(3).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, summary.Coefficients(), ols.Coefficients(), 1e-8)
+
+	// predictions work on raw rows
+	model, summary, err := NewPCRTrainer(1).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, round(model.Predict(data[3]), 6), round(summary.Yhat()[3], 6))
+
+	// the variance threshold picks the number of components
+	_, summary, err = NewPCRVarianceTrainer(explained[0]+explained[1]).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, summary.(PCRSummary).Components())
+
+	_, _, err = NewPCRTrainer(4).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,160 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Partial least squares (PLS1) regression builds components that, unlike
+// principal components, are chosen for their covariance with y. Using the
+// NIPALS algorithm on the standardized X and centered y, for m = 1 .. M:
+//
+// w_m = X' y / ||X' y||		weights
+// t_m = X w_m				scores
+// p_m = X' t_m / t_m' t_m	loadings
+// q_m = y' t_m / t_m' t_m
+//
+// then X and y are deflated: X = X - t_m p_m', y = y - q_m t_m.
+//
+// The coefficients on the predictors are \beta = W (P'W)^-1 q. Only an M x M
+// system is solved, so there may be far more (collinear) columns than rows.
+type PLS struct {
+	betas []float64
+}
+
+func (p *PLS) Predict(x []float64) float64 {
+	return p.betas[0] + sum(prod(x, p.betas[1:]))
+}
+
+type plsTrainer struct {
+	m int
+}
+
+// NewPLSTrainer returns a Trainer for partial least squares regression with m
+// components.
+func NewPLSTrainer(m int) Trainer {
+	return &plsTrainer{
+		m: m,
+	}
+}
+
+// PLSSummary is the summary of a partial least squares regression.
+type PLSSummary struct {
+	OlsSummary
+	weights   *DataFrame
+	loadings  *DataFrame
+	scores    *DataFrame
+	yloadings []float64
+}
+
+// Weights returns the p x m matrix W of weights, one column per component.
+func (p PLSSummary) Weights() *DataFrame { return p.weights }
+
+// Loadings returns the p x m matrix P of X loadings, one column per component.
+func (p PLSSummary) Loadings() *DataFrame { return p.loadings }
+
+// Scores returns the n x m matrix T of scores, one column per component.
+func (p PLSSummary) Scores() *DataFrame { return p.scores }
+
+// YLoadings returns the loading q_m of y on each component.
+func (p PLSSummary) YLoadings() []float64 { return p.yloadings }
+
+func (p *plsTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n, c := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if p.m < 1 || p.m > c || p.m > n-1 {
+		return nil, nil, fmt.Errorf("number of components must be between 1 and min(p, n-1)")
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+
+	// standardize, and keep a copy of the columns to deflate
+	cols, means, sds := scaleCols(x)
+	r := subtractMean(y)
+
+	var (
+		w = mat64.NewDense(c, p.m, nil)
+		l = mat64.NewDense(c, p.m, nil)
+		t = mat64.NewDense(n, p.m, nil)
+		q = make([]float64, p.m)
+	)
+
+	for m := 0; m < p.m; m++ {
+		// w = X' y / ||X' y||
+		wm := make([]float64, c)
+		for j, col := range cols {
+			wm[j] = dot(col, r)
+		}
+		norm := math.Sqrt(dot(wm, wm))
+		if norm == 0 {
+			return nil, nil, fmt.Errorf("y is orthogonal to X after %d components", m)
+		}
+		wm = multSlice(wm, 1/norm)
+
+		// t = X w
+		tm := make([]float64, n)
+		for j, col := range cols {
+			for i, v := range col {
+				tm[i] += v * wm[j]
+			}
+		}
+		tt := dot(tm, tm)
+
+		// p = X' t / t't, q = y' t / t't
+		pm := make([]float64, c)
+		for j, col := range cols {
+			pm[j] = dot(col, tm) / tt
+		}
+		q[m] = dot(r, tm) / tt
+
+		// deflate
+		for j, col := range cols {
+			for i := range col {
+				col[i] -= tm[i] * pm[j]
+			}
+		}
+		for i := range r {
+			r[i] -= q[m] * tm[i]
+		}
+
+		w.SetCol(m, wm)
+		l.SetCol(m, pm)
+		t.SetCol(m, tm)
+	}
+
+	// \beta = W (P'W)^-1 q
+	pw := &mat64.Dense{}
+	pw.Mul(l.T(), w)
+	z := &mat64.Dense{}
+	if err := z.Solve(pw, mat64.NewDense(p.m, 1, q)); err != nil {
+		return nil, nil, err
+	}
+	b := &mat64.Dense{}
+	b.Mul(w, z)
+
+	betas := unscaleBetas(mat64.Col(nil, 0, b), means, sds, mean(y))
+	fitted, residuals := linearFit(x, betas, response)
+
+	return &PLS{
+		betas: betas,
+	}, PLSSummary{
+		OlsSummary: OlsSummary{
+			betas:     betas,
+			residuals: residuals,
+			fitted:    fitted,
+			response:  response,
+			n:         n,
+			p:         c,
+			data:      withIntercept(x),
+		},
+		weights:   Mat64ToDF(w),
+		loadings:  Mat64ToDF(l),
+		scores:    Mat64ToDF(t),
+		yloadings: q,
+	}, nil
+}
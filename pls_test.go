@@ -0,0 +1,45 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestPLS(t *testing.T) {
+	// all components give back least squares
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, summary, err := NewPLSTrainer(3).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, summary.Coefficients(), ols.Coefficients(), 1e-8)
+
+	// the scores are orthogonal
+	scores := summary.(PLSSummary).Scores().Data()
+	s0, s1 := mat64.Col(nil, 0, scores), mat64.Col(nil, 1, scores)
+	assert.T(t, math.Abs(dot(s0, s1)) < 1e-8)
+
+	_, _, err = NewPLSTrainer(4).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestPLSWide(t *testing.T) {
+	// more collinear columns than rows
+	wide := make([][]float64, len(data))
+	for i, row := range data {
+		for k := 0; k < 10; k++ {
+			wide[i] = append(wide[i], row[k%3]*float64(k+1)+math.Sin(float64(i*k)))
+		}
+	}
+
+	model, summary, err := NewPLSTrainer(2).Train(NewDataFrame(wide[:8]), y[:8])
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 11, len(summary.Coefficients()))
+	assert.Equal(t, round(model.Predict(wide[0]), 6), round(summary.Yhat()[0], 6))
+
+	weights := summary.(PLSSummary).Weights()
+	assert.Equal(t, 10, weights.Rows())
+	assert.Equal(t, 2, weights.Cols())
+}
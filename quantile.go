@@ -0,0 +1,527 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Quantile regression estimates the tau-th quantile of y given x by
+// minimizing the check loss
+//
+// sum rho_tau(y_i - x_i β),		rho_tau(e) = e (tau - I(e < 0))
+//
+// which weights positive residuals by tau and negative ones by 1 - tau, so
+// that tau = 0.5 gives least absolute deviations, the conditional median.
+//
+// Writing the residuals as e = u - v with u, v >= 0 makes this the linear
+// program
+//
+// min tau 1'u + (1 - tau) 1'v		s.t. X β + u - v = y
+//
+// which is solved exactly by the Barrodale-Roberts simplex method (see
+// quantileBR). At the solution, at least p of the residuals are zero.
+type Quantile struct {
+	tau   float64
+	betas []float64
+}
+
+func (q *Quantile) Predict(x []float64) float64 {
+	return q.betas[0] + sum(prod(x, q.betas[1:]))
+}
+
+// Tau returns the quantile the model estimates.
+func (q *Quantile) Tau() float64 { return q.tau }
+
+type quantileTrainer struct {
+	tau        float64
+	replicates int
+	seed       int64
+}
+
+// NewQuantileTrainer returns a Trainer for the tau-th conditional quantile,
+// 0 < tau < 1. The standard errors are estimated from replicates bootstrap
+// resamples of the rows, drawn from a generator seeded with seed; if
+// replicates is not positive they are not computed.
+func NewQuantileTrainer(tau float64, replicates int, seed int64) Trainer {
+	return &quantileTrainer{
+		tau:        tau,
+		replicates: replicates,
+		seed:       seed,
+	}
+}
+
+// QuantileSummary is the summary of a quantile regression fit.
+type QuantileSummary struct {
+	tau       float64
+	betas     []float64
+	residuals []float64
+	fitted    []float64
+	response  []float64
+	stdErrs   []float64
+	data      *DataFrame
+}
+
+func (q QuantileSummary) Data() *DataFrame        { return q.data }
+func (q QuantileSummary) Coefficients() []float64 { return q.betas }
+func (q QuantileSummary) Residuals() []float64    { return q.residuals }
+func (q QuantileSummary) Yhat() []float64         { return q.fitted }
+func (q QuantileSummary) Response() []float64     { return q.response }
+
+func (q QuantileSummary) SumOfSquares() float64 {
+	return sum(prod(q.residuals, q.residuals))
+}
+
+// Tau returns the quantile that was fit.
+func (q QuantileSummary) Tau() float64 { return q.tau }
+
+// StdErrors returns the bootstrap standard errors of the coefficients, or nil
+// if they were not computed.
+func (q QuantileSummary) StdErrors() []float64 { return q.stdErrs }
+
+// CheckLoss returns the minimized sum rho_tau(e_i).
+func (q QuantileSummary) CheckLoss() float64 {
+	return checkLoss(q.residuals, q.tau)
+}
+
+// PseudoRSquared returns Koenker and Machado's R1 = 1 - V / V0, where V is
+// the check loss of the fit and V0 that of the unconditional tau-th quantile.
+func (q QuantileSummary) PseudoRSquared() float64 {
+	v0 := math.Inf(1)
+	// the unconditional quantile is one of the observations
+	for _, c := range q.response {
+		v0 = math.Min(v0, checkLoss(subSlice(q.response, c), q.tau))
+	}
+	return 1 - q.CheckLoss()/v0
+}
+
+func (q QuantileSummary) String() string {
+	return fmt.Sprintf(`
+		Tau: %v
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+
+		Check loss: %v
+		Pseudo R-squared: %v`,
+		q.tau,
+		roundAll(q.betas),
+		roundAll(q.stdErrs),
+		round(q.CheckLoss(), 4),
+		round(q.PseudoRSquared(), 4),
+	)
+}
+
+func (q *quantileTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if !(q.tau > 0 && q.tau < 1) {
+		return nil, nil, fmt.Errorf("tau must be in (0, 1), got %v", q.tau)
+	}
+	data := withIntercept(x)
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = data.GetRow(i)
+	}
+
+	betas, err := quantileBR(rows, y, q.tau)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stdErrs []float64
+	if q.replicates > 0 {
+		stdErrs, err = quantileBootstrap(rows, y, q.tau, q.replicates, q.seed)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	response := make([]float64, n)
+	copy(response, y)
+	fitted, residuals := linearFit(x, betas, response)
+
+	return &Quantile{
+		tau:   q.tau,
+		betas: betas,
+	}, QuantileSummary{
+		tau:       q.tau,
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		stdErrs:   stdErrs,
+		data:      data,
+	}, nil
+}
+
+// FitQuantiles fits a quantile regression for each of taus, with standard
+// errors from the same bootstrap resamples.
+func FitQuantiles(x *DataFrame, y, taus []float64, replicates int, seed int64) ([]Model, []QuantileSummary, error) {
+	models := make([]Model, len(taus))
+	summaries := make([]QuantileSummary, len(taus))
+	for k, tau := range taus {
+		m, s, err := NewQuantileTrainer(tau, replicates, seed).Train(x, y)
+		if err != nil {
+			return nil, nil, err
+		}
+		models[k] = m
+		summaries[k] = s.(QuantileSummary)
+	}
+	return models, summaries, nil
+}
+
+func checkLoss(e []float64, tau float64) float64 {
+	loss := 0.0
+	for _, v := range e {
+		if v < 0 {
+			loss += (tau - 1) * v
+		} else {
+			loss += tau * v
+		}
+	}
+	return loss
+}
+
+// quantileBootstrap returns the standard deviations of the coefficients over
+// resamples of the rows with replacement.
+func quantileBootstrap(rows [][]float64, y []float64, tau float64, replicates int, seed int64) ([]float64, error) {
+	var (
+		n     = len(rows)
+		p     = len(rows[0])
+		rnd   = rand.New(rand.NewSource(seed))
+		sums  = make([]float64, p)
+		sumSq = make([]float64, p)
+		xb    = make([][]float64, n)
+		yb    = make([]float64, n)
+	)
+	for r := 0; r < replicates; r++ {
+		for i := range xb {
+			k := rnd.Intn(n)
+			xb[i], yb[i] = rows[k], y[k]
+		}
+		b, err := quantileBR(xb, yb, tau)
+		if err != nil {
+			return nil, err
+		}
+		for j, v := range b {
+			sums[j] += v
+			sumSq[j] += v * v
+		}
+	}
+
+	se := make([]float64, p)
+	if replicates < 2 {
+		return se, nil
+	}
+	R := float64(replicates)
+	for j := range se {
+		se[j] = math.Sqrt(math.Max(sumSq[j]-sums[j]*sums[j]/R, 0) / (R - 1))
+	}
+	return se, nil
+}
+
+// tolerance on the optimality conditions and on pivot elements
+const simplexEpsilon = 1e-9
+
+// quantileBR solves the quantile regression linear program by the simplex
+// method of Barrodale and Roberts (1974), as in R's quantreg. A vertex of the
+// program is a fit through p of the observations, the basis h, so only the
+// p x p matrix X_h is ever factored rather than a tableau over the n slack
+// pairs (u, v), which stay implicit in the signs of the residuals.
+//
+// At a vertex, with psi(e) = tau - I(e < 0), the fit is optimal when
+//
+// xi = -(X_h')^-1 sum_{i not in h} psi(e_i) x_i
+//
+// lies in [tau - 1, tau]^p. Otherwise the observation k of the most violated
+// condition leaves the basis, and the fit moves along the edge keeping the
+// rest of the basis interpolated. The check loss is piecewise linear along the
+// edge, with a kink where each residual changes sign, and rather than stopping
+// at the first kink as the plain simplex would, the step goes to the kink where
+// the slope turns positive: a weighted median, passing several vertices in a
+// pivot. The observation at that kink enters the basis.
+//
+// Ties, as from rows repeated in a bootstrap resample, make vertices
+// degenerate, where a pivot need not lower the loss and the simplex can cycle.
+// They are broken by perturbing y by a tiny fixed amount, and the final basis
+// is solved with the original y, which is a vertex of the unperturbed program
+// and, for a small enough perturbation, optimal.
+//
+// Columns that are linearly dependent on those before them get a coefficient
+// of 0, and the fit starts from the p observations with the smallest least
+// squares residuals that give a nonsingular X_h.
+func quantileBR(rows [][]float64, y []float64, tau float64) ([]float64, error) {
+	n, p := len(rows), len(rows[0])
+
+	cols := independentCols(rows)
+	q := len(cols)
+	betas := make([]float64, p)
+	if q == 0 {
+		return betas, nil
+	}
+	x := make([][]float64, n)
+	for i, row := range rows {
+		x[i] = make([]float64, q)
+		for j, c := range cols {
+			x[i][j] = row[c]
+		}
+	}
+
+	size := 1.0
+	for _, v := range y {
+		size = math.Max(size, math.Abs(v))
+	}
+	rnd := rand.New(rand.NewSource(1))
+	yp := make([]float64, n)
+	for i, v := range y {
+		yp[i] = v + 1e-10*size*(2*rnd.Float64()-1)
+	}
+
+	// start from the least squares residuals
+	ls := mat64.NewDense(n, q, nil)
+	for i, row := range x {
+		ls.SetRow(i, row)
+	}
+	b, err := leastSquares(ls, y)
+	if err != nil {
+		return nil, err
+	}
+	e := make([]float64, n)
+	for i, row := range x {
+		e[i] = y[i] - dot(row, b)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return math.Abs(e[order[a]]) < math.Abs(e[order[b]]) })
+	basis := independentRows(x, order, q)
+
+	var (
+		inBasis = make([]bool, n)
+		a       = make([]float64, n)
+		kinks   = make([]int, 0, n)
+		g       = make([]float64, q)
+		xh      = make([][]float64, q)
+		yh      = make([]float64, q)
+	)
+	for it := 0; ; it++ {
+		if it > 10*n+100 {
+			return nil, fmt.Errorf("simplex did not converge")
+		}
+
+		for k, i := range basis {
+			inBasis[i] = true
+			xh[k], yh[k] = x[i], yp[i]
+		}
+		inv, err := invertRows(xh)
+		if err != nil {
+			return nil, err
+		}
+		// b = X_h^-1 y_h
+		for j := range b {
+			b[j] = dot(inv[j], yh)
+		}
+
+		// g = sum_{i not in h} psi(e_i) x_i, a zero residual counting as
+		// positive
+		for j := range g {
+			g[j] = 0
+		}
+		for i, row := range x {
+			if inBasis[i] {
+				e[i] = 0
+				continue
+			}
+			e[i] = yp[i] - dot(row, b)
+			psi := tau
+			if e[i] < 0 {
+				psi = tau - 1
+			}
+			for j, v := range row {
+				g[j] += psi * v
+			}
+		}
+
+		// xi_k = -(X_h^-1' g)_k; the slope of the loss along the edge
+		// dropping k is xi_k + 1 - tau if its residual turns negative and
+		// tau - xi_k if it turns positive
+		leave, sigma, slope := -1, 0.0, -simplexEpsilon
+		for k := range basis {
+			xi := 0.0
+			for j := range g {
+				xi -= inv[j][k] * g[j]
+			}
+			if s := xi + 1 - tau; s < slope {
+				leave, sigma, slope = k, 1, s
+			}
+			if s := tau - xi; s < slope {
+				leave, sigma, slope = k, -1, s
+			}
+		}
+		if leave < 0 {
+			break
+		}
+
+		// along b + t d, with X_h d = sigma e_k, residual i moves as
+		// e_i - t a_i; its kink is at t = e_i / a_i
+		kinks = kinks[:0]
+		for i, row := range x {
+			if inBasis[i] {
+				continue
+			}
+			a[i] = 0
+			for j, v := range row {
+				a[i] += sigma * v * inv[j][leave]
+			}
+			if (e[i] >= 0 && a[i] > simplexEpsilon) || (e[i] < 0 && a[i] < -simplexEpsilon) {
+				kinks = append(kinks, i)
+			}
+		}
+		sort.Slice(kinks, func(u, v int) bool {
+			return e[kinks[u]]/a[kinks[u]] < e[kinks[v]]/a[kinks[v]]
+		})
+
+		// each kink raises the slope by |a_i|
+		enter := -1
+		for _, i := range kinks {
+			slope += math.Abs(a[i])
+			if slope >= 0 {
+				enter = i
+				break
+			}
+		}
+		if enter < 0 {
+			return nil, fmt.Errorf("quantile regression problem is unbounded")
+		}
+		inBasis[basis[leave]] = false
+		basis[leave] = enter
+	}
+
+	// the vertex of the unperturbed program
+	for k, i := range basis {
+		yh[k] = y[i]
+	}
+	inv, err := invertRows(xh)
+	if err != nil {
+		return nil, err
+	}
+	for j, c := range cols {
+		betas[c] = dot(inv[j], yh)
+	}
+	return betas, nil
+}
+
+// independentCols returns the columns of the rows that are not linearly
+// dependent on the columns before them, by Gram-Schmidt.
+func independentCols(rows [][]float64) []int {
+	n, p := len(rows), len(rows[0])
+	var (
+		cols []int
+		q    [][]float64
+	)
+	for j := 0; j < p; j++ {
+		v := make([]float64, n)
+		for i, row := range rows {
+			v[i] = row[j]
+		}
+		norm := math.Sqrt(dot(v, v))
+		for _, u := range q {
+			c := dot(u, v)
+			for i := range v {
+				v[i] -= c * u[i]
+			}
+		}
+		if r := math.Sqrt(dot(v, v)); r > 1e-10*norm && r > 0 {
+			for i := range v {
+				v[i] /= r
+			}
+			q = append(q, v)
+			cols = append(cols, j)
+		}
+	}
+	return cols
+}
+
+// independentRows returns the first p of the rows, taken in order, that are
+// linearly independent, by Gram-Schmidt.
+func independentRows(x [][]float64, order []int, p int) []int {
+	var (
+		rows []int
+		q    [][]float64
+	)
+	for _, i := range order {
+		v := append([]float64(nil), x[i]...)
+		norm := math.Sqrt(dot(v, v))
+		for _, u := range q {
+			c := dot(u, v)
+			for j := range v {
+				v[j] -= c * u[j]
+			}
+		}
+		if r := math.Sqrt(dot(v, v)); r > 1e-8*norm && r > 0 {
+			for j := range v {
+				v[j] /= r
+			}
+			q = append(q, v)
+			rows = append(rows, i)
+			if len(rows) == p {
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// invertRows inverts the square matrix with the given rows by Gauss-Jordan
+// elimination with partial pivoting.
+func invertRows(a [][]float64) ([][]float64, error) {
+	p := len(a)
+	m := make([][]float64, p)
+	inv := make([][]float64, p)
+	scale := 0.0
+	for i, row := range a {
+		m[i] = append([]float64(nil), row...)
+		inv[i] = make([]float64, p)
+		inv[i][i] = 1
+		for _, v := range row {
+			scale = math.Max(scale, math.Abs(v))
+		}
+	}
+	for c := 0; c < p; c++ {
+		piv := c
+		for r := c + 1; r < p; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[piv][c]) {
+				piv = r
+			}
+		}
+		if math.Abs(m[piv][c]) <= 1e-14*scale {
+			return nil, fmt.Errorf("singular basis in the simplex")
+		}
+		m[c], m[piv] = m[piv], m[c]
+		inv[c], inv[piv] = inv[piv], inv[c]
+		f := m[c][c]
+		for j := 0; j < p; j++ {
+			m[c][j] /= f
+			inv[c][j] /= f
+		}
+		for r := 0; r < p; r++ {
+			if r == c || m[r][c] == 0 {
+				continue
+			}
+			f := m[r][c]
+			for j := 0; j < p; j++ {
+				m[r][j] -= f * m[c][j]
+				inv[r][j] -= f * inv[c][j]
+			}
+		}
+	}
+	return inv, nil
+}
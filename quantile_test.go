@@ -0,0 +1,145 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+// least absolute deviations on stackloss, as rq(stack.loss ~ ., tau = .5)
+// in R's quantreg
+func TestQuantileStackloss(t *testing.T) {
+	_, summary, err := NewQuantileTrainer(0.5, 0, 0).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{-39.68986, 0.83188, 0.57391, -0.06087}, summary.Coefficients(), 1e-5)
+
+	// the solution interpolates p = 4 points
+	zeros := 0
+	for _, e := range summary.Residuals() {
+		if math.Abs(e) < 1e-8 {
+			zeros++
+		}
+	}
+	assert.Equal(t, true, zeros >= 4)
+	assert.Equal(t, 0, len(summary.(QuantileSummary).StdErrors()))
+}
+
+// with one predictor the optimum passes through two of the points, so it is
+// the best of the lines through every pair
+func TestQuantileExhaustive(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	obs := []float64{1.2, 2.9, 2.1, 5.5, 4.4, 7.9, 6.1, 9.8, 8.0, 13.5, 10.2}
+	rows := make([][]float64, len(x))
+	for i, v := range x {
+		rows[i] = []float64{v}
+	}
+
+	for _, tau := range []float64{0.1, 0.25, 0.5, 0.9} {
+		_, summary, err := NewQuantileTrainer(tau, 0, 0).Train(NewDataFrame(rows), obs)
+		assert.Equal(t, nil, err)
+
+		best := math.Inf(1)
+		for i := range x {
+			for j := i + 1; j < len(x); j++ {
+				slope := (obs[j] - obs[i]) / (x[j] - x[i])
+				icept := obs[i] - slope*x[i]
+				e := make([]float64, len(x))
+				for k := range e {
+					e[k] = obs[k] - icept - slope*x[k]
+				}
+				best = math.Min(best, checkLoss(e, tau))
+			}
+		}
+		assertNear(t, []float64{best}, []float64{summary.(QuantileSummary).CheckLoss()}, 1e-9)
+	}
+}
+
+// with a predictor that is always zero the fit is a sample quantile
+func TestQuantileIntercept(t *testing.T) {
+	obs := []float64{5, 3, 9, 1, 7, 2, 8, 6, 4}
+	rows := make([][]float64, len(obs))
+	for i := range rows {
+		rows[i] = []float64{0}
+	}
+	_, summary, err := NewQuantileTrainer(0.5, 0, 0).Train(NewDataFrame(rows), obs)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{5, 0}, summary.Coefficients(), 1e-10)
+
+	_, summary, err = NewQuantileTrainer(0.2, 0, 0).Train(NewDataFrame(rows), obs)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{2, 0}, summary.Coefficients(), 1e-10)
+
+	_, _, err = NewQuantileTrainer(1, 0, 0).Train(NewDataFrame(rows), obs)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestFitQuantiles(t *testing.T) {
+	taus := []float64{0.1, 0.5, 0.9}
+	models, summaries, err := FitQuantiles(NewDataFrame(data), y, taus, 50, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(models))
+
+	// the quantiles are ordered at the center of the data
+	center := []float64{60.43, 21.1, 86.3}
+	preds := make([]float64, len(models))
+	for k, m := range models {
+		preds[k] = m.Predict(center)
+		assert.Equal(t, taus[k], summaries[k].Tau())
+
+		se := summaries[k].StdErrors()
+		assert.Equal(t, 4, len(se))
+		for _, v := range se {
+			assert.Equal(t, true, v > 0)
+		}
+	}
+	assert.Equal(t, true, sort.Float64sAreSorted(preds))
+
+	// the bootstrap is reproducible from the seed
+	_, again, err := FitQuantiles(NewDataFrame(data), y, taus[1:2], 50, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, summaries[1].StdErrors(), again[0].StdErrors())
+}
+
+// skewed, heteroscedastic latencies: the fit and its bootstrap must stay fast
+// at sizes where a dense simplex tableau would not fit in memory
+func TestQuantileLarge(t *testing.T) {
+	n := 5000
+	rnd := rand.New(rand.NewSource(2))
+	rows := make([][]float64, n)
+	latency := make([]float64, n)
+	for i := range rows {
+		load, region := 10*rnd.Float64(), float64(rnd.Intn(4))
+		rows[i] = []float64{load, region}
+		latency[i] = 5 + 2*load + region + math.Exp(rnd.NormFloat64())*(1+load/5)
+	}
+
+	start := time.Now()
+	_, summary, err := NewQuantileTrainer(0.9, 20, 1).Train(NewDataFrame(rows), latency)
+	elapsed := time.Since(start)
+	assert.Equal(t, nil, err)
+	if elapsed > 10*time.Second {
+		t.Errorf("fit with 20 bootstrap replicates took %v", elapsed)
+	}
+
+	// at the optimum at most n tau residuals are negative and at most
+	// n (1 - tau) positive
+	var neg, pos int
+	for _, e := range summary.Residuals() {
+		switch {
+		case e < -1e-8:
+			neg++
+		case e > 1e-8:
+			pos++
+		}
+	}
+	assert.Equal(t, true, float64(neg) <= 0.9*float64(n))
+	assert.Equal(t, true, float64(pos) <= 0.1*float64(n))
+
+	for _, se := range summary.(QuantileSummary).StdErrors() {
+		assert.Equal(t, true, se > 0)
+	}
+}
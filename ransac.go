@@ -0,0 +1,168 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// RansacConfig configures RANSAC (random sample consensus), which fits a
+// model in the presence of many outliers. It repeatedly trains on a small
+// random subset of the rows and counts the rows the fit predicts to within a
+// threshold, its consensus set. The largest consensus set is taken as the
+// inliers, and the model refit on all of them.
+type RansacConfig struct {
+	Trainer    Trainer // fits each subset and the final inliers
+	MinSamples int     // rows in each random subset; predictors + 1 if not positive
+	Threshold  float64 // largest absolute residual of an inlier; the MAD of y if not positive
+	MaxIt      int     // number of random subsets tried
+	Seed       int64   // seed of the random subsets
+}
+
+// NewRansacConfig returns a config for RANSAC around trainer with the default
+// subset size and threshold.
+func NewRansacConfig(trainer Trainer, maxIt int, seed int64) *RansacConfig {
+	return &RansacConfig{
+		Trainer: trainer,
+		MaxIt:   maxIt,
+		Seed:    seed,
+	}
+}
+
+type ransacTrainer struct {
+	config *RansacConfig
+}
+
+// NewRansacTrainer returns a Trainer for RANSAC.
+func NewRansacTrainer(config *RansacConfig) Trainer {
+	return &ransacTrainer{
+		config: config,
+	}
+}
+
+// RansacSummary is the summary of a RANSAC fit. The embedded Summary is that
+// of the wrapped trainer on the inliers alone.
+type RansacSummary struct {
+	Summary
+	inliers   []bool
+	threshold float64
+}
+
+// Inliers returns whether each row is in the final consensus set.
+func (r RansacSummary) Inliers() []bool { return r.inliers }
+
+// Threshold returns the largest absolute residual of an inlier.
+func (r RansacSummary) Threshold() float64 { return r.threshold }
+
+func (r RansacSummary) String() string {
+	count := 0
+	for _, in := range r.inliers {
+		if in {
+			count++
+		}
+	}
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+
+		Inliers: %d of %d
+		Threshold: %v`,
+		roundAll(r.Coefficients()),
+		count,
+		len(r.inliers),
+		round(r.threshold, 4),
+	)
+}
+
+func (r *ransacTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	c := r.config
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if c.Trainer == nil {
+		return nil, nil, fmt.Errorf("RANSAC needs a trainer")
+	}
+
+	k := c.MinSamples
+	if k <= 0 {
+		k = x.Cols() + 1
+	}
+	if k > n {
+		return nil, nil, fmt.Errorf("%d rows are too few for subsets of %d", n, k)
+	}
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = mad(y)
+	}
+
+	var (
+		rnd      = rand.New(rand.NewSource(c.Seed))
+		rows     = make([][]float64, n)
+		best     []bool
+		bestSize int
+		bestRSS  = math.Inf(1)
+	)
+	for i := range rows {
+		rows[i] = x.GetRow(i)
+	}
+	for it := 0; it < c.MaxIt; it++ {
+		sample := rnd.Perm(n)[:k]
+		model, _, err := c.Trainer.Train(subsetRows(x, rows, sample), subsetOf(y, sample))
+		if err != nil {
+			// a degenerate subset; try another
+			continue
+		}
+
+		inliers := make([]bool, n)
+		size, rss := 0, 0.0
+		for i, row := range rows {
+			e := math.Abs(y[i] - model.Predict(row))
+			if e <= threshold {
+				inliers[i] = true
+				size++
+				rss += e * e
+			}
+		}
+		if size > bestSize || (size == bestSize && rss < bestRSS) {
+			best, bestSize, bestRSS = inliers, size, rss
+		}
+	}
+	if bestSize < k {
+		return nil, nil, fmt.Errorf("no consensus set of at least %d rows within %v", k, threshold)
+	}
+
+	var keep []int
+	for i, in := range best {
+		if in {
+			keep = append(keep, i)
+		}
+	}
+	model, summary, err := c.Trainer.Train(subsetRows(x, rows, keep), subsetOf(y, keep))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return model, RansacSummary{
+		Summary:   summary,
+		inliers:   best,
+		threshold: threshold,
+	}, nil
+}
+
+// subsetRows returns a DataFrame of the given rows of x.
+func subsetRows(x *DataFrame, rows [][]float64, idx []int) *DataFrame {
+	sub := make([][]float64, len(idx))
+	for i, k := range idx {
+		sub[i] = rows[k]
+	}
+	return NewDataFrame(sub, x.Labels())
+}
+
+func subsetOf(y []float64, idx []int) []float64 {
+	sub := make([]float64, len(idx))
+	for i, k := range idx {
+		sub[i] = y[k]
+	}
+	return sub
+}
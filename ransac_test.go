@@ -0,0 +1,42 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestRansac(t *testing.T) {
+	// y = 1 + 2 x1 - x2, with a third of the rows corrupted
+	var (
+		x   [][]float64
+		obs []float64
+	)
+	for i := 0; i < 30; i++ {
+		x1, x2 := float64(i%7), float64(i%5)
+		x = append(x, []float64{x1, x2})
+		v := 1 + 2*x1 - x2
+		if i%3 == 0 {
+			v += 20 + float64(i)
+		}
+		obs = append(obs, v)
+	}
+
+	config := NewRansacConfig(NewOlsTrainer(), 50, 1)
+	config.Threshold = 0.5
+	model, summary, err := NewRansacTrainer(config).Train(NewDataFrame(x), obs)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{1, 2, -1}, summary.Coefficients(), 1e-10)
+	assertNear(t, []float64{1 + 2*3 - 4}, []float64{model.Predict([]float64{3, 4})}, 1e-10)
+
+	rs := summary.(RansacSummary)
+	for i, in := range rs.Inliers() {
+		assert.Equal(t, i%3 != 0, in)
+	}
+	// the refit sees only the inliers
+	assert.Equal(t, 20, len(rs.Residuals()))
+
+	config.MinSamples = 31
+	_, _, err = NewRansacTrainer(config).Train(NewDataFrame(x), obs)
+	assert.NotEqual(t, nil, err)
+}
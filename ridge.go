@@ -1,6 +1,9 @@
 package glasso
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/gonum/matrix"
 	"github.com/gonum/matrix/mat64"
 )
@@ -13,18 +16,13 @@ import (
 // Using Single value Decomposition, we can easily solve for Beta_ridge
 // X = UDVt
 //
-// We can solve for Beta_ridge using:
-// X Beta_ridge = U D(D2 + \lambda I)−1D UT y
+// Beta_ridge = V D(D2 + \lambda I)−1 UT y
+// X Beta_ridge = U D2(D2 + \lambda I)−1 UT y
 //
-// type ridgeSummary struct {
-// 	x          *DataFrame
-// 	lambda     float64
-// 	n, c       int
-// 	fitted     []float64
-// 	residuals  []float64
-// 	response   []float64
-// 	beta_ridge []float64
-// }
+// The columns of X are standardized and y centered, so the intercept is not
+// penalized; the coefficients are reported on the original scale. Since the
+// SVD does not depend on lambda, every lambda costs only O(np) once it is
+// computed.
 type Ridge struct {
 	betas []float64
 }
@@ -33,76 +31,231 @@ func (r *Ridge) Predict(x []float64) float64 {
 	return r.betas[0] + sum(prod(x, r.betas[1:]))
 }
 
+// RidgeCriterion is the criterion used to choose lambda.
+type RidgeCriterion uint8
+
+const (
+	GCV   RidgeCriterion = iota // generalized cross-validation
+	LOOCV                       // leave-one-out cross-validation
+)
+
 // x = n x c
 // U = n x c
 // D = c x c
 // V = c x c
 type ridgeTrainer struct {
-	lambda float64
+	lambdas   []float64
+	criterion RidgeCriterion
 }
 
-// Ridge regression for model shrinkage
+// NewRidgeTrainer returns a Trainer for ridge regression with penalty lambda.
 //
 // Larger lambda equals more shrinkage of the variables.
 // lambda -> 0 equals the least squares solution
 // lambda -> oo means all coeffients equal 0
-func (r *ridgeTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
-	n, c := x.Data().Dims()
-	var (
-		fitted     = make([]float64, n)
-		residuals  = make([]float64, n)
-		beta_ridge = make([]float64, c)
+func NewRidgeTrainer(lambda float64) Trainer {
+	return &ridgeTrainer{
+		lambdas: []float64{lambda},
+	}
+}
+
+// NewRidgeCVTrainer returns a Trainer for ridge regression that picks the
+// lambda minimizing the criterion over lambdas. If lambdas is nil, 100 values
+// are used, spaced evenly on the log scale from 10 d_1^2 down to 1e-4 d_1^2,
+// where d_1 is the largest singular value of the standardized X.
+func NewRidgeCVTrainer(lambdas []float64, criterion RidgeCriterion) Trainer {
+	return &ridgeTrainer{
+		lambdas:   lambdas,
+		criterion: criterion,
+	}
+}
+
+// RidgeSummary is the summary of a ridge regression, with the cross-validation
+// scores of every lambda tried.
+type RidgeSummary struct {
+	betas     []float64
+	residuals []float64
+	fitted    []float64
+	response  []float64
+	lambda    float64
+	df        float64
+	lambdas   []float64
+	dfs       []float64
+	scores    []float64
+	data      *DataFrame
+}
+
+func (r RidgeSummary) Data() *DataFrame        { return r.data }
+func (r RidgeSummary) Coefficients() []float64 { return r.betas }
+func (r RidgeSummary) Residuals() []float64    { return r.residuals }
+func (r RidgeSummary) Yhat() []float64         { return r.fitted }
+func (r RidgeSummary) Response() []float64     { return r.response }
+
+func (r RidgeSummary) SumOfSquares() float64 {
+	return sum(prod(r.residuals, r.residuals))
+}
+
+// Lambda returns the penalty of the fit.
+func (r RidgeSummary) Lambda() float64 { return r.lambda }
+
+// Df returns the effective degrees of freedom of the fit,
+// \sum d_j^2 / (d_j^2 + \lambda), not counting the intercept.
+func (r RidgeSummary) Df() float64 { return r.df }
+
+// Lambdas returns the penalties tried.
+func (r RidgeSummary) Lambdas() []float64 { return r.lambdas }
+
+// EffectiveDf returns the effective degrees of freedom at each lambda tried.
+func (r RidgeSummary) EffectiveDf() []float64 { return r.dfs }
+
+// Scores returns the GCV or LOOCV mean squared error at each lambda tried.
+func (r RidgeSummary) Scores() []float64 { return r.scores }
+
+func (r RidgeSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+
+		Lambda: %v
+		Effective df: %v
+		RSS: %v`,
+		roundAll(r.betas),
+		round(r.lambda, 4),
+		round(r.df, 3),
+		round(r.SumOfSquares(), 3),
 	)
+}
 
-	// standarize matrix and have y_bar = 0
-	x.Normalize()
-	y = subtractMean(y)
-	response := y
-	svd := &mat64.SVD{}
-	svd.Factorize(mat64.DenseCopyOf(x.Data()), matrix.SVDFull)
+// Ridge regression for model shrinkage. With h_ii = 1/n + \sum_j U_ij^2 d_j^2
+// / (d_j^2 + \lambda) the diagonal of the hat matrix, including the intercept,
+//
+// GCV = RSS / n / (1 - tr(H)/n)^2
+// LOOCV = 1/n \sum (e_i / (1 - h_ii))^2
+func (r *ridgeTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n, c := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+
+	// standardize the columns and center y
+	cols, means, sds := scaleCols(x)
+	z := mat64.NewDense(n, c, nil)
+	for j, col := range cols {
+		z.SetCol(j, col)
+	}
+	yc := subtractMean(y)
 
-	U := (&mat64.Dense{})
+	svd := &mat64.SVD{}
+	if ok := svd.Factorize(z, matrix.SVDThin); !ok {
+		return nil, nil, fmt.Errorf("SVD failed to converge")
+	}
+	U := &mat64.Dense{}
 	U.UFromSVD(svd)
-	V := (&mat64.Dense{})
+	V := &mat64.Dense{}
 	V.VFromSVD(svd)
 	d := svd.Values(nil)
+	k := len(d)
 
-	// convert the c x c diagonal matrix D into a mat64.Dense matrix
-	D := mat64.NewDense(c, c, rep(0.0, c*c))
-	for i := 0; i < c; i++ {
-		val := d[i] / (d[i] + r.lambda)
-		D.Set(i, i, val)
+	// Ut y
+	uty := make([]float64, k)
+	for j := range uty {
+		uty[j] = dot(mat64.Col(nil, j, U), yc)
 	}
 
-	// solve for beta_ridge
-	betaMat := &mat64.Dense{}
-	betaMat.Mul(V, D)
-	betaMat.Mul(betaMat, U.T())
-	Y := mat64.NewDense(len(y), 1, y)
-	betaMat.Mul(betaMat, Y)
+	lambdas := r.lambdas
+	if lambdas == nil {
+		lambdas = make([]float64, 100)
+		for i := range lambdas {
+			lambdas[i] = 10 * d[0] * d[0] * math.Pow(1e-5, float64(i)/99)
+		}
+	}
+	for _, l := range lambdas {
+		if l < 0 {
+			return nil, nil, fmt.Errorf("lambda must be non-negative")
+		}
+	}
+
+	// the shrinkage d_j^2 / (d_j^2 + \lambda) of each direction
+	shrink := func(lambda float64) []float64 {
+		s := make([]float64, k)
+		for j, v := range d {
+			if v*v+lambda > 0 {
+				s[j] = v * v / (v*v + lambda)
+			}
+		}
+		return s
+	}
+	// centered fitted values U diag(s) Ut y
+	fit := func(s []float64) []float64 {
+		f := make([]float64, n)
+		for j := range s {
+			a := s[j] * uty[j]
+			for i := range f {
+				f[i] += U.At(i, j) * a
+			}
+		}
+		return f
+	}
 
-	// save beta values
-	beta_ridge = mat64.Col(nil, 0, betaMat)
+	var (
+		dfs    = make([]float64, len(lambdas))
+		scores = make([]float64, len(lambdas))
+		best   int
+	)
+	for l, lambda := range lambdas {
+		s := shrink(lambda)
+		dfs[l] = sum(s)
 
-	// find the fitted values : X * \beta_ridge
-	fittedMat := &mat64.Dense{}
-	fittedMat.Mul(x.Data(), betaMat)
-	fitted = mat64.Col(nil, 0, fittedMat)
+		e := diff(yc, fit(s))
+		switch r.criterion {
+		case LOOCV:
+			for i := range e {
+				h := 1 / float64(n)
+				for j := range s {
+					h += U.At(i, j) * U.At(i, j) * s[j]
+				}
+				scores[l] += (e[i] / (1 - h)) * (e[i] / (1 - h))
+			}
+			scores[l] /= float64(n)
+		default:
+			tr := 1 - (1+dfs[l])/float64(n)
+			scores[l] = dot(e, e) / float64(n) / (tr * tr)
+		}
+		if scores[l] < scores[best] {
+			best = l
+		}
+	}
+
+	// beta = V diag(d / (d^2 + \lambda)) Ut y, on the standardized scale
+	lambda := lambdas[best]
+	b := make([]float64, c)
+	for j, v := range d {
+		if v*v+lambda == 0 {
+			continue
+		}
+		a := v / (v*v + lambda) * uty[j]
+		for i := range b {
+			b[i] += V.At(i, j) * a
+		}
+	}
+	betas := unscaleBetas(b, means, sds, mean(y))
 
-	// get residuals
-	fittedMat.Sub(fittedMat, Y)
-	residuals = mat64.Col(nil, 0, fittedMat)
+	response := make([]float64, n)
+	copy(response, y)
+	fitted, residuals := linearFit(x, betas, response)
 
 	return &Ridge{
-			betas: beta_ridge,
-		}, OlsSummary{
-			data: x,
-			//lambda:     r.lambda,
-			n:         n,
-			p:         c,
-			fitted:    fitted,
-			residuals: residuals,
-			response:  response,
-			betas:     beta_ridge,
-		}, nil
+		betas: betas,
+	}, RidgeSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		lambda:    lambda,
+		df:        dfs[best],
+		lambdas:   lambdas,
+		dfs:       dfs,
+		scores:    scores,
+		data:      withIntercept(x),
+	}, nil
 }
@@ -0,0 +1,112 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestRidgeOLS(t *testing.T) {
+	// no penalty is least squares, intercept included
+	_, summary, err := NewRidgeTrainer(0).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, ols.Coefficients(), summary.Coefficients(), 1e-8)
+	assertNear(t, ols.Residuals(), summary.Residuals(), 1e-8)
+	assertNear(t, []float64{3}, []float64{summary.(RidgeSummary).Df()}, 1e-10)
+
+	_, _, err = NewRidgeTrainer(-1).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+}
+
+// the SVD solution matches (Zt Z + lambda I)^-1 Zt y on the standardized
+// columns
+func TestRidgeNormalEquations(t *testing.T) {
+	lambda := 5.0
+	_, summary, err := NewRidgeTrainer(lambda).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	cols, means, sds := scaleCols(NewDataFrame(data))
+	z := mat64.NewDense(len(y), 3, nil)
+	for j, col := range cols {
+		z.SetCol(j, col)
+	}
+	ztz := &mat64.Dense{}
+	ztz.Mul(z.T(), z)
+	for j := 0; j < 3; j++ {
+		ztz.Set(j, j, ztz.At(j, j)+lambda)
+	}
+	zty := &mat64.Dense{}
+	zty.Mul(z.T(), mat64.NewDense(len(y), 1, subtractMean(y)))
+	b := &mat64.Dense{}
+	assert.Equal(t, nil, b.Solve(ztz, zty))
+	want := unscaleBetas(mat64.Col(nil, 0, b), means, sds, mean(y))
+	assertNear(t, want, summary.Coefficients(), 1e-10)
+
+	// residuals are y - yhat
+	rs := summary.(RidgeSummary)
+	for i, e := range rs.Residuals() {
+		assertNear(t, []float64{y[i] - rs.Yhat()[i]}, []float64{e}, 1e-12)
+	}
+	assert.Equal(t, true, rs.Df() > 0 && rs.Df() < 3)
+}
+
+func TestRidgeCV(t *testing.T) {
+	for _, criterion := range []RidgeCriterion{GCV, LOOCV} {
+		_, summary, err := NewRidgeCVTrainer(nil, criterion).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		rs := summary.(RidgeSummary)
+		assert.Equal(t, 100, len(rs.Lambdas()))
+
+		// the effective df falls from almost 3 to almost 0 as lambda grows
+		dfs := rs.EffectiveDf()
+		for k := 1; k < len(dfs); k++ {
+			assert.Equal(t, true, dfs[k] > dfs[k-1])
+		}
+		assert.Equal(t, true, dfs[0] < 0.5 && dfs[99] > 2.99)
+
+		best := 0
+		for k, s := range rs.Scores() {
+			if s < rs.Scores()[best] {
+				best = k
+			}
+		}
+		assert.Equal(t, rs.Lambdas()[best], rs.Lambda())
+		assert.Equal(t, dfs[best], rs.Df())
+	}
+}
+
+// the LOOCV shortcut agrees with the hat matrix built directly
+func TestRidgeLOOCV(t *testing.T) {
+	lambda := 2.0
+	_, summary, err := NewRidgeCVTrainer([]float64{lambda}, LOOCV).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	n := len(y)
+	cols, _, _ := scaleCols(NewDataFrame(data))
+	z := mat64.NewDense(n, 3, nil)
+	for j, col := range cols {
+		z.SetCol(j, col)
+	}
+	ztz := &mat64.Dense{}
+	ztz.Mul(z.T(), z)
+	for j := 0; j < 3; j++ {
+		ztz.Set(j, j, ztz.At(j, j)+lambda)
+	}
+	inv := &mat64.Dense{}
+	assert.Equal(t, nil, inv.Inverse(ztz))
+	hz := &mat64.Dense{}
+	hz.Mul(z, inv)
+	h := &mat64.Dense{}
+	h.Mul(hz, z.T())
+
+	cv := 0.0
+	e := summary.Residuals()
+	for i := 0; i < n; i++ {
+		r := e[i] / (1 - 1/float64(n) - h.At(i, i))
+		cv += r * r / float64(n)
+	}
+	assertNear(t, []float64{cv}, summary.(RidgeSummary).Scores(), 1e-8)
+}
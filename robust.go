@@ -0,0 +1,332 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Robust regression by M-estimation minimizes sum rho((y_i - x_i β) / s) for
+// a loss rho that grows more slowly than the square, so that outliers pull
+// less on the fit. Setting the derivative to zero gives
+//
+// sum psi(u_i) x_i = 0,		u_i = (y_i - x_i β) / s
+//
+// which is a weighted least squares problem with weights w_i = psi(u_i) / u_i.
+// It is solved by iteratively reweighted least squares, starting from OLS (or,
+// for a redescending psi, from a Huber fit) and re-estimating the scale s from
+// the median absolute deviation (MAD) of the residuals at each step.
+type Robust struct {
+	betas []float64
+}
+
+func (r *Robust) Predict(x []float64) float64 {
+	return r.betas[0] + sum(prod(x, r.betas[1:]))
+}
+
+// A Psi is the derivative of the loss of an M-estimator.
+type Psi struct {
+	Name         string
+	PsiFn        evalFn // psi(u)
+	WeightFn     evalFn // psi(u) / u
+	DerivativeFn evalFn // psi'(u)
+
+	// Redescending is set when psi returns to zero for large |u|.
+	Redescending bool
+}
+
+// HuberPsi returns Huber's psi, which is linear for |u| <= k and constant
+// beyond it; observations past k get weight k / |u|.
+func HuberPsi(k float64) Psi {
+	return Psi{
+		Name: "huber",
+		PsiFn: func(u float64) float64 {
+			return clamp(u, -k, k)
+		},
+		WeightFn: func(u float64) float64 {
+			if math.Abs(u) <= k {
+				return 1
+			}
+			return k / math.Abs(u)
+		},
+		DerivativeFn: func(u float64) float64 {
+			if math.Abs(u) <= k {
+				return 1
+			}
+			return 0
+		},
+	}
+}
+
+// BisquarePsi returns Tukey's bisquare psi, u (1 - (u/c)^2)^2 for |u| <= c and
+// 0 beyond it, so that observations past c are ignored.
+func BisquarePsi(c float64) Psi {
+	return Psi{
+		Name:         "bisquare",
+		Redescending: true,
+		PsiFn: func(u float64) float64 {
+			if math.Abs(u) > c {
+				return 0
+			}
+			t := 1 - (u/c)*(u/c)
+			return u * t * t
+		},
+		WeightFn: func(u float64) float64 {
+			if math.Abs(u) > c {
+				return 0
+			}
+			t := 1 - (u/c)*(u/c)
+			return t * t
+		},
+		DerivativeFn: func(u float64) float64 {
+			if math.Abs(u) > c {
+				return 0
+			}
+			t := (u / c) * (u / c)
+			return (1 - t) * (1 - 5*t)
+		},
+	}
+}
+
+// The tuning constants give 95% efficiency at the normal distribution.
+var (
+	Huber    = HuberPsi(1.345)
+	Bisquare = BisquarePsi(4.685)
+)
+
+// the MAD of a normal sample, divided by this, estimates its standard deviation
+const madConstant = 0.6744897501960817
+
+type robustTrainer struct {
+	psi   Psi
+	maxIt int
+}
+
+// NewRobustTrainer returns a Trainer for M-estimation with the given psi,
+// running at most maxIt IRLS iterations (50 if not positive).
+func NewRobustTrainer(psi Psi, maxIt int) Trainer {
+	if maxIt <= 0 {
+		maxIt = 50
+	}
+	return &robustTrainer{
+		psi:   psi,
+		maxIt: maxIt,
+	}
+}
+
+// RobustSummary is the summary of an M-estimation fit. Residuals and Yhat are
+// on the scale of y, and the diagnostics treat the fit as unweighted.
+type RobustSummary struct {
+	betas      []float64
+	residuals  []float64
+	fitted     []float64
+	response   []float64
+	weights    []float64
+	stdErrs    []float64
+	scale      float64
+	iterations int
+	data       *DataFrame
+}
+
+func (r RobustSummary) Data() *DataFrame        { return r.data }
+func (r RobustSummary) Coefficients() []float64 { return r.betas }
+func (r RobustSummary) Residuals() []float64    { return r.residuals }
+func (r RobustSummary) Yhat() []float64         { return r.fitted }
+func (r RobustSummary) Response() []float64     { return r.response }
+
+func (r RobustSummary) SumOfSquares() float64 {
+	return sum(prod(r.residuals, r.residuals))
+}
+
+// RobustnessWeights returns the weight psi(u_i) / u_i of each observation in
+// the final fit; outliers have weights near (or, for the bisquare, at) zero.
+func (r RobustSummary) RobustnessWeights() []float64 { return r.weights }
+
+// Scale returns the final MAD estimate of the residual standard deviation.
+func (r RobustSummary) Scale() float64 { return r.scale }
+
+// StdErrors returns the sandwich standard errors of the coefficients.
+func (r RobustSummary) StdErrors() []float64 { return r.stdErrs }
+
+// Iterations returns the number of IRLS iterations run with psi, not counting
+// the Huber fit a redescending psi starts from.
+func (r RobustSummary) Iterations() int { return r.iterations }
+
+func (r RobustSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+		Std. Errors:
+		%v
+
+		Scale: %v
+		Iterations: %d`,
+		roundAll(r.betas),
+		roundAll(r.stdErrs),
+		round(r.scale, 4),
+		r.iterations,
+	)
+}
+
+func (r *robustTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	data := withIntercept(x)
+	p := data.Cols()
+	if n <= p {
+		return nil, nil, fmt.Errorf("need more observations than coefficients")
+	}
+
+	betas, err := leastSquares(data.Data(), y)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.psi.Redescending {
+		// a redescending psi has several local solutions, and one that starts
+		// from OLS can keep the outliers that pulled OLS toward them; start it
+		// from a Huber fit instead, as MASS::rlm does
+		betas, _, _, _, _, err = mEstimate(x, data, y, betas, Huber, r.maxIt)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	betas, residuals, weights, s, iters, err := mEstimate(x, data, y, betas, r.psi, r.maxIt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stdErrs, err := sandwich(data.Data(), residuals, s, r.psi)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fitted, _ := linearFit(x, betas, y)
+	response := make([]float64, n)
+	copy(response, y)
+
+	return &Robust{
+		betas: betas,
+	}, RobustSummary{
+		betas:      betas,
+		residuals:  residuals,
+		fitted:     fitted,
+		response:   response,
+		weights:    weights,
+		stdErrs:    stdErrs,
+		scale:      s,
+		iterations: iters,
+		data:       data,
+	}, nil
+}
+
+// mEstimate runs at most maxIt iterations of M-estimation with psi from betas, and
+// returns the coefficients, residuals, weights and scale of the last step.
+func mEstimate(x, data *DataFrame, y, betas []float64, psi Psi, maxIt int) ([]float64, []float64, []float64, float64, int, error) {
+	n, p := data.Rows(), data.Cols()
+	_, residuals := linearFit(x, betas, y)
+
+	var (
+		s       float64
+		weights = rep(1.0, n)
+		iters   int
+		err     error
+	)
+	for iters < maxIt {
+		iters++
+		s = mad(residuals) / madConstant
+		if s == 0 {
+			// at least half the points are fit exactly
+			break
+		}
+		for i, e := range residuals {
+			weights[i] = psi.WeightFn(e / s)
+		}
+
+		xw := data.Data()
+		yw := make([]float64, n)
+		for i, w := range weights {
+			sw := math.Sqrt(w)
+			for j := 0; j < p; j++ {
+				xw.Set(i, j, sw*xw.At(i, j))
+			}
+			yw[i] = sw * y[i]
+		}
+		betas, err = leastSquares(xw, yw)
+		if err != nil {
+			return nil, nil, nil, 0, 0, err
+		}
+
+		old := residuals
+		_, residuals = linearFit(x, betas, y)
+		change := diff(residuals, old)
+		if math.Sqrt(dot(change, change)) <= DefaultTolerance*math.Max(math.Sqrt(dot(old, old)), 1) {
+			break
+		}
+	}
+	return betas, residuals, weights, s, iters, nil
+}
+
+// sandwich returns the standard errors from Huber's sandwich estimate
+//
+// var(β) = n / (n - p) s^2 A^-1 B A^-1
+//
+// with A = sum psi'(u_i) x_i x_i' and B = sum psi(u_i)^2 x_i x_i'.
+func sandwich(x *mat64.Dense, residuals []float64, s float64, psi Psi) ([]float64, error) {
+	n, p := x.Dims()
+	a := mat64.NewDense(p, p, nil)
+	b := mat64.NewDense(p, p, nil)
+	for i, e := range residuals {
+		u := 0.0
+		if s > 0 {
+			u = e / s
+		}
+		d, f := psi.DerivativeFn(u), psi.PsiFn(u)
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				xx := x.At(i, j) * x.At(i, k)
+				a.Set(j, k, a.At(j, k)+d*xx)
+				b.Set(j, k, b.At(j, k)+f*f*xx)
+			}
+		}
+	}
+
+	ainv := &mat64.Dense{}
+	if err := ainv.Inverse(a); err != nil {
+		return nil, err
+	}
+	ab := &mat64.Dense{}
+	ab.Mul(ainv, b)
+	cov := &mat64.Dense{}
+	cov.Mul(ab, ainv)
+
+	c := float64(n) / float64(n-p) * s * s
+	stdErrs := make([]float64, p)
+	for j := range stdErrs {
+		stdErrs[j] = math.Sqrt(c * cov.At(j, j))
+	}
+	return stdErrs, nil
+}
+
+// mad returns the median absolute deviation from the median.
+func mad(x []float64) float64 {
+	m := median(x)
+	dev := make([]float64, len(x))
+	for i, v := range x {
+		dev[i] = math.Abs(v - m)
+	}
+	return median(dev)
+}
+
+func median(x []float64) float64 {
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
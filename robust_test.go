@@ -0,0 +1,110 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// y = 1 + 2x with small errors, and two gross outliers
+var (
+	robustX = [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}, {10}, {11}, {12}}
+	robustY = []float64{3.1, 4.9, 7.2, 8.8, 11.1, 13.0, 14.9, 17.2, 40.0, 20.8, 23.1, -5.0}
+)
+
+func TestRobustOutliers(t *testing.T) {
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(robustX), robustY)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, math.Abs(ols.Coefficients()[1]-2) > 0.3)
+
+	for _, psi := range []Psi{Huber, Bisquare} {
+		_, summary, err := NewRobustTrainer(psi, 0).Train(NewDataFrame(robustX), robustY)
+		assert.Equal(t, nil, err)
+		rs := summary.(RobustSummary)
+
+		assertNear(t, []float64{1, 2}, rs.Coefficients(), 0.3)
+		w := rs.RobustnessWeights()
+		for i, v := range w {
+			if i == 8 || i == 11 {
+				assert.Equal(t, true, v < 0.1)
+			} else {
+				assert.Equal(t, true, v > 0.5)
+			}
+		}
+		if psi.Name == "bisquare" {
+			assert.Equal(t, 0.0, w[8])
+			assert.Equal(t, 0.0, w[11])
+		}
+		assert.Equal(t, true, rs.Scale() < 1)
+	}
+}
+
+// y = 1 + 2x for x in 1..20, and two points far out in x that pull OLS almost
+// flat
+var (
+	leverageX = [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}, {10}, {11}, {12}, {13}, {14}, {15}, {16}, {17}, {18}, {19}, {20}, {40}, {41}}
+	leverageY = []float64{3, 5.6, 6.7, 9.3, 10.4, 13, 15.6, 16.7, 19.3, 20.4, 23, 25.6, 26.7, 29.3, 30.4, 33, 35.6, 36.7, 39.3, 40.4, 0, 0}
+)
+
+func TestRobustLeverage(t *testing.T) {
+	x := NewDataFrame(leverageX)
+	data := withIntercept(x)
+	ols, err := leastSquares(data.Data(), leverageY)
+	assert.Equal(t, nil, err)
+
+	// from OLS the bisquare settles on a line through the leverage points
+	betas, _, _, _, _, err := mEstimate(x, data, leverageY, ols, Bisquare, 50)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, math.Abs(betas[1]-2) > 1)
+
+	// from the Huber fit it finds the line through the other twenty
+	_, summary, err := NewRobustTrainer(Bisquare, 0).Train(x, leverageY)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{1, 2}, summary.Coefficients(), 0.1)
+	w := summary.(RobustSummary).RobustnessWeights()
+	assert.Equal(t, 0.0, w[20])
+	assert.Equal(t, 0.0, w[21])
+}
+
+// with a tuning constant no residual reaches, the fit is OLS and the sandwich
+// is the HC1 heteroskedasticity-consistent covariance
+func TestRobustSandwich(t *testing.T) {
+	_, summary, err := NewRobustTrainer(HuberPsi(1e6), 0).Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+	assertNear(t, ols.Coefficients(), summary.Coefficients(), 1e-10)
+
+	// for a single predictor, (X'X)^-1 X' diag(e^2) X (X'X)^-1 in closed form
+	var (
+		n    = float64(len(wlsY))
+		e    = ols.Residuals()
+		x    = make([]float64, len(wlsY))
+		xbar float64
+	)
+	for i, row := range wlsX {
+		x[i] = row[0]
+		xbar += row[0] / n
+	}
+	var sxx, slope, icept float64
+	for i := range x {
+		sxx += (x[i] - xbar) * (x[i] - xbar)
+	}
+	for i := range x {
+		// beta = sum c_i y_i with these c_i
+		cs := (x[i] - xbar) / sxx
+		ci := 1/n - xbar*cs
+		slope += cs * cs * e[i] * e[i]
+		icept += ci * ci * e[i] * e[i]
+	}
+	hc1 := n / (n - 2)
+	want := []float64{math.Sqrt(hc1 * icept), math.Sqrt(hc1 * slope)}
+	assertNear(t, want, summary.(RobustSummary).StdErrors(), 1e-8)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 3.0, median([]float64{5, 1, 3}))
+	assert.Equal(t, 2.5, median([]float64{4, 1, 3, 2}))
+	assert.Equal(t, 1.0, mad([]float64{1, 2, 3, 4, 100}))
+}
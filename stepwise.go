@@ -0,0 +1,325 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ematvey/gostat"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Direction of a stepwise search.
+type Direction uint8
+
+const (
+	Forward  Direction = iota // start empty and add columns
+	Backward                  // start full and remove columns
+	Both                      // start empty, and add or remove at each step
+)
+
+// Criterion used to compare models in a stepwise search.
+type Criterion uint8
+
+const (
+	CriterionAIC   Criterion = iota // lowest AIC
+	CriterionBIC                    // lowest BIC
+	CriterionFTest                  // partial F-test p-values
+)
+
+// The StepwiseConfig specifies the search direction and the criterion used to
+// decide which column enters or leaves the model at each step.
+type StepwiseConfig struct {
+	Direction Direction
+	Criterion Criterion
+	Enter     float64 // p-value below which a column may enter (F-test only)
+	Remove    float64 // p-value above which a column may leave (F-test only)
+	MaxSteps  int     // upper bound on the number of steps, 10p if not positive
+}
+
+// NewStepwiseConfig returns a config entering columns at p < 0.05 and
+// removing them at p > 0.10 when the F-test criterion is used.
+func NewStepwiseConfig(dir Direction, crit Criterion) *StepwiseConfig {
+	return &StepwiseConfig{
+		Direction: dir,
+		Criterion: crit,
+		Enter:     0.05,
+		Remove:    0.10,
+	}
+}
+
+// StepwiseStep records a single column entering or leaving the model.
+type StepwiseStep struct {
+	Column  int     // column of the original DataFrame
+	Label   string  // label of the column, if the DataFrame has labels
+	Entered bool    // true if the column entered, false if it left
+	Before  float64 // criterion before the step (AIC, BIC, or the p-value)
+	After   float64 // criterion after the step; equal to Before for the F-test
+}
+
+func (s StepwiseStep) String() string {
+	name := s.Label
+	if name == "" {
+		name = fmt.Sprintf("column %d", s.Column)
+	}
+	if s.Before == s.After {
+		if s.Entered {
+			return fmt.Sprintf("+ %s (p = %.4g)", name, s.Before)
+		}
+		return fmt.Sprintf("- %s (p = %.4g)", name, s.Before)
+	}
+	if s.Entered {
+		return fmt.Sprintf("+ %s (%.4g -> %.4g)", name, s.Before, s.After)
+	}
+	return fmt.Sprintf("- %s (%.4g -> %.4g)", name, s.Before, s.After)
+}
+
+// StepwiseResult holds the selected columns, the final fit and the trace of
+// the search.
+type StepwiseResult struct {
+	Columns []int    // selected columns of the original DataFrame
+	Labels  []string // labels of the selected columns, if any
+	Model   Model
+	Summary Summary
+	Trace   []StepwiseStep
+}
+
+// Stepwise selects a subset of the columns of x for the trainer by adding
+// or removing one column at a time.
+//
+// With AIC or BIC, the candidate giving the lowest criterion is taken as long
+// as it improves on the current model. With the F-test, the candidate column
+// with the smallest p-value enters if it is below config.Enter, and the
+// column with the largest p-value leaves if it is above config.Remove. The
+// partial F statistic for a single column is
+//
+// F = (RSS_small - RSS_big) / (RSS_big / (n - p_big))
+//
+// on 1 and n - p_big degrees of freedom.
+func Stepwise(trainer Trainer, x *DataFrame, y []float64, config *StepwiseConfig) (*StepwiseResult, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config not set")
+	}
+	if len(y) != x.Rows() {
+		return nil, DimensionError
+	}
+	if config.Criterion == CriterionFTest && config.Enter > config.Remove {
+		return nil, fmt.Errorf("enter threshold must not exceed remove threshold")
+	}
+
+	s := &stepper{
+		trainer: trainer,
+		x:       x,
+		y:       y,
+		config:  config,
+		in:      make([]bool, x.Cols()),
+	}
+	if config.Direction == Backward {
+		for j := range s.in {
+			s.in[j] = true
+		}
+	}
+
+	current, err := s.fit(s.in)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSteps := config.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 10 * x.Cols()
+	}
+
+	var trace []StepwiseStep
+	for step := 0; step < maxSteps; step++ {
+		var (
+			next *stepFit
+			move StepwiseStep
+		)
+
+		if config.Direction != Backward {
+			next, move, err = s.add(current)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// when searching both ways, AIC and BIC take whichever move is
+		// better, while the F-test only removes once nothing can enter
+		if config.Direction != Forward && (next == nil || config.Criterion != CriterionFTest) {
+			rnext, rmove, err := s.remove(current)
+			if err != nil {
+				return nil, err
+			}
+			if rnext != nil && (next == nil || rmove.After < move.After) {
+				next, move = rnext, rmove
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		s.in[move.Column] = move.Entered
+		if len(x.Labels()) == x.Cols() {
+			move.Label = x.Labels()[move.Column]
+		}
+		trace = append(trace, move)
+		current = next
+	}
+
+	result := &StepwiseResult{
+		Model:   current.model,
+		Summary: current.summary,
+		Trace:   trace,
+	}
+	for j, ok := range s.in {
+		if ok {
+			result.Columns = append(result.Columns, j)
+			if len(x.Labels()) == x.Cols() {
+				result.Labels = append(result.Labels, x.Labels()[j])
+			}
+		}
+	}
+	return result, nil
+}
+
+type stepper struct {
+	trainer Trainer
+	x       *DataFrame
+	y       []float64
+	config  *StepwiseConfig
+	in      []bool
+}
+
+type stepFit struct {
+	model   Model
+	summary Summary
+}
+
+// fit trains the model on the included columns. The intercept-only model is
+// fit directly, since there are no columns to hand to the trainer.
+func (s *stepper) fit(in []bool) (*stepFit, error) {
+	var cols []int
+	for j, ok := range in {
+		if ok {
+			cols = append(cols, j)
+		}
+	}
+
+	if len(cols) == 0 {
+		n := s.x.Rows()
+		ybar := mean(s.y)
+		response := make([]float64, n)
+		copy(response, s.y)
+		return &stepFit{
+			model: &OLS{betas: []float64{ybar}},
+			summary: OlsSummary{
+				betas:     []float64{ybar},
+				residuals: subSlice(s.y, ybar),
+				fitted:    rep(ybar, n),
+				response:  response,
+				n:         n,
+				data:      Mat64ToDF(mat64.NewDense(n, 1, rep(1., n))),
+			},
+		}, nil
+	}
+
+	df, err := s.x.SelectCols(cols...)
+	if err != nil {
+		return nil, err
+	}
+	model, summary, err := s.trainer.Train(df, s.y)
+	if err != nil {
+		return nil, err
+	}
+	return &stepFit{model: model, summary: summary}, nil
+}
+
+func (s *stepper) score(f *stepFit) float64 {
+	if s.config.Criterion == CriterionBIC {
+		return BIC(f.summary)
+	}
+	return AIC(f.summary)
+}
+
+// partialF returns the p-value of the F-test comparing the nested models.
+func partialF(small, big Summary) float64 {
+	n := float64(big.Data().Rows())
+	d2 := n - float64(big.Data().Cols())
+	rss := big.SumOfSquares()
+	f := (small.SumOfSquares() - rss) / (rss / d2)
+	if d2 <= 0 || math.IsNaN(f) {
+		return 1
+	}
+	return 1 - stat.F_CDF(1, d2)(math.Max(f, 0))
+}
+
+// add returns the best model with one more column, or nil if no column
+// improves the criterion enough to enter.
+func (s *stepper) add(current *stepFit) (*stepFit, StepwiseStep, error) {
+	return s.try(current, false)
+}
+
+// remove returns the best model with one column fewer, or nil if no column
+// should leave.
+func (s *stepper) remove(current *stepFit) (*stepFit, StepwiseStep, error) {
+	return s.try(current, true)
+}
+
+func (s *stepper) try(current *stepFit, remove bool) (*stepFit, StepwiseStep, error) {
+	var (
+		best     *stepFit
+		bestStep StepwiseStep
+		bestVal  float64
+	)
+	before := s.score(current)
+
+	for j := range s.in {
+		if s.in[j] != remove {
+			continue
+		}
+
+		in := make([]bool, len(s.in))
+		copy(in, s.in)
+		in[j] = !remove
+		candidate, err := s.fit(in)
+		if err != nil {
+			return nil, StepwiseStep{}, err
+		}
+
+		var val float64
+		switch s.config.Criterion {
+		case CriterionFTest:
+			// compare p-values; negate on removal so smaller is better
+			if remove {
+				val = -partialF(candidate.summary, current.summary)
+			} else {
+				val = partialF(current.summary, candidate.summary)
+			}
+		default:
+			val = s.score(candidate)
+		}
+
+		if best == nil || val < bestVal {
+			best, bestVal = candidate, val
+			bestStep = StepwiseStep{Column: j, Entered: !remove}
+		}
+	}
+	if best == nil {
+		return nil, StepwiseStep{}, nil
+	}
+
+	if s.config.Criterion == CriterionFTest {
+		p := math.Abs(bestVal)
+		if (!remove && p >= s.config.Enter) || (remove && p <= s.config.Remove) {
+			return nil, StepwiseStep{}, nil
+		}
+		bestStep.Before, bestStep.After = p, p
+		return best, bestStep, nil
+	}
+
+	if bestVal >= before {
+		return nil, StepwiseStep{}, nil
+	}
+	bestStep.Before, bestStep.After = before, bestVal
+	return best, bestStep, nil
+}
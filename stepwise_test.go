@@ -0,0 +1,37 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestStepwise(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+
+	// acid concentration is not worth its parameter in any direction
+	for _, dir := range []Direction{Forward, Backward, Both} {
+		for _, crit := range []Criterion{CriterionAIC, CriterionBIC, CriterionFTest} {
+			config := NewStepwiseConfig(dir, crit)
+			result, err := Stepwise(NewOlsTrainer(), NewDataFrame(data, labels), y, config)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, []int{0, 1}, result.Columns)
+			assert.Equal(t, []string{"Air.Flow", "Water.Temp"}, result.Labels)
+			assert.Equal(t, 3, len(result.Summary.Coefficients()))
+		}
+	}
+
+	// the trace records why each column moved
+	result, err := Stepwise(NewOlsTrainer(), NewDataFrame(data, labels), y, NewStepwiseConfig(Backward, CriterionAIC))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(result.Trace))
+	step := result.Trace[0]
+	assert.Equal(t, 2, step.Column)
+	assert.Equal(t, false, step.Entered)
+	assert.T(t, step.After < step.Before)
+
+	result, err = Stepwise(NewOlsTrainer(), NewDataFrame(data, labels), y, NewStepwiseConfig(Forward, CriterionFTest))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "Air.Flow", result.Trace[0].Label)
+	assert.T(t, result.Trace[0].Before < 0.05)
+}
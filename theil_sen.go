@@ -0,0 +1,124 @@
+package glasso
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Theil-Sen regression fits a line to a single predictor with the median of
+// the slopes between every pair of points,
+//
+// b1 = median (y_j - y_i) / (x_j - x_i),		x_i != x_j
+//
+// and the intercept b0 = median(y_i - b1 x_i). It tolerates up to 29% of the
+// points being arbitrary outliers. For large n the slopes of a random sample
+// of the pairs stand in for all n(n-1)/2 of them.
+type TheilSen struct {
+	betas []float64
+}
+
+func (t *TheilSen) Predict(x []float64) float64 {
+	return t.betas[0] + t.betas[1]*x[0]
+}
+
+type theilSenTrainer struct {
+	maxPairs int
+	seed     int64
+}
+
+// NewTheilSenTrainer returns a Trainer for Theil-Sen regression. If there are
+// more than maxPairs pairs of points, the slope is the median over maxPairs
+// pairs drawn at random from a generator seeded with seed; if maxPairs is not
+// positive every pair is used.
+func NewTheilSenTrainer(maxPairs int, seed int64) Trainer {
+	return &theilSenTrainer{
+		maxPairs: maxPairs,
+		seed:     seed,
+	}
+}
+
+// TheilSenSummary is the summary of a Theil-Sen fit.
+type TheilSenSummary struct {
+	betas     []float64
+	residuals []float64
+	fitted    []float64
+	response  []float64
+	pairs     int
+	data      *DataFrame
+}
+
+func (t TheilSenSummary) Data() *DataFrame        { return t.data }
+func (t TheilSenSummary) Coefficients() []float64 { return t.betas }
+func (t TheilSenSummary) Residuals() []float64    { return t.residuals }
+func (t TheilSenSummary) Yhat() []float64         { return t.fitted }
+func (t TheilSenSummary) Response() []float64     { return t.response }
+
+func (t TheilSenSummary) SumOfSquares() float64 {
+	return sum(prod(t.residuals, t.residuals))
+}
+
+// Pairs returns the number of pairwise slopes the slope is the median of.
+func (t TheilSenSummary) Pairs() int { return t.pairs }
+
+func (t TheilSenSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+
+		Pairs: %d`,
+		roundAll(t.betas),
+		t.pairs,
+	)
+}
+
+func (t *theilSenTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
+	n := df.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if df.Cols() != 1 {
+		return nil, nil, fmt.Errorf("Theil-Sen needs a single predictor, got %d", df.Cols())
+	}
+	x := df.GetCol(0)
+
+	var slopes []float64
+	if t.maxPairs <= 0 || n*(n-1)/2 <= t.maxPairs {
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if x[i] != x[j] {
+					slopes = append(slopes, (y[j]-y[i])/(x[j]-x[i]))
+				}
+			}
+		}
+	} else {
+		rnd := rand.New(rand.NewSource(t.seed))
+		slopes = make([]float64, 0, t.maxPairs)
+		for tries := 0; len(slopes) < t.maxPairs && tries < 10*t.maxPairs; tries++ {
+			i, j := rnd.Intn(n), rnd.Intn(n)
+			if x[i] != x[j] {
+				slopes = append(slopes, (y[j]-y[i])/(x[j]-x[i]))
+			}
+		}
+	}
+	if len(slopes) == 0 {
+		return nil, nil, fmt.Errorf("need at least two distinct values of the predictor")
+	}
+
+	slope := median(slopes)
+	betas := []float64{median(diff(y, multSlice(x, slope))), slope}
+
+	response := make([]float64, n)
+	copy(response, y)
+	fitted, residuals := linearFit(df, betas, response)
+
+	return &TheilSen{
+		betas: betas,
+	}, TheilSenSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		pairs:     len(slopes),
+		data:      withIntercept(df),
+	}, nil
+}
@@ -0,0 +1,55 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestTheilSen(t *testing.T) {
+	x := [][]float64{{1}, {2}, {3}, {4}, {5}}
+	obs := []float64{2, 4, 7, 8, 10}
+
+	// slopes 2, 2.5, 2, 2, 3, 2, 2, 1, 1.5, 2 have median 2; y - 2x is
+	// 0, 0, 1, 0, 0
+	_, summary, err := NewTheilSenTrainer(0, 0).Train(NewDataFrame(x), obs)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{0, 2}, summary.Coefficients(), 1e-12)
+	assert.Equal(t, 10, summary.(TheilSenSummary).Pairs())
+
+	// a wild point among nine on a line does not move the fit: it makes 8 of
+	// the 36 slopes
+	x = [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}}
+	obs = []float64{2, 4, 6, 8, 100, 12, 14, 16, 18}
+	_, summary, err = NewTheilSenTrainer(0, 0).Train(NewDataFrame(x), obs)
+	assert.Equal(t, nil, err)
+	assertNear(t, []float64{0, 2}, summary.Coefficients(), 1e-12)
+
+	_, _, err = NewTheilSenTrainer(0, 0).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewTheilSenTrainer(0, 0).Train(NewDataFrame([][]float64{{1}, {1}}), []float64{1, 2})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestTheilSenRandomized(t *testing.T) {
+	n := 400
+	x := make([][]float64, n)
+	obs := make([]float64, n)
+	for i := range x {
+		x[i] = []float64{float64(i)}
+		obs[i] = 3 + 0.5*float64(i)
+		if i%5 == 0 {
+			obs[i] += 1000
+		}
+	}
+
+	_, summary, err := NewTheilSenTrainer(2000, 1).Train(NewDataFrame(x), obs)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2000, summary.(TheilSenSummary).Pairs())
+	assertNear(t, []float64{3, 0.5}, summary.Coefficients(), 1e-12)
+
+	// the same seed draws the same pairs
+	_, again, err := NewTheilSenTrainer(2000, 1).Train(NewDataFrame(x), obs)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, summary.Coefficients(), again.Coefficients())
+}
@@ -0,0 +1,131 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Weighted Least Squares regression minimizes sum w_i (y_i - x_i β)^2, for
+// observations with unequal variances var(y_i) = σ^2 / w_i, such as means
+// over groups of different sizes.
+//
+// Scaling the rows of X and y by sqrt(w) turns this into ordinary least
+// squares: with W^1/2 X = QR, Rβ = Qt W^1/2 y.
+type WLS struct {
+	betas []float64
+}
+
+func (w *WLS) Predict(x []float64) float64 {
+	return w.betas[0] + sum(prod(x, w.betas[1:]))
+}
+
+type wlsTrainer struct {
+	weights []float64
+}
+
+// NewWlsTrainer returns a Trainer for weighted least squares with a positive
+// weight for each row.
+func NewWlsTrainer(weights []float64) Trainer {
+	return &wlsTrainer{
+		weights: weights,
+	}
+}
+
+// WlsSummary is the summary of a weighted least squares fit. Residuals and
+// Yhat are on the scale of y; SumOfSquares is the weighted residual sum of
+// squares, so that the diagnostics estimate σ^2 from it.
+type WlsSummary struct {
+	betas     []float64
+	residuals []float64
+	fitted    []float64
+	response  []float64
+	weights   []float64
+	data      *DataFrame
+}
+
+func (w WlsSummary) Data() *DataFrame        { return w.data }
+func (w WlsSummary) Coefficients() []float64 { return w.betas }
+func (w WlsSummary) Residuals() []float64    { return w.residuals }
+func (w WlsSummary) Yhat() []float64         { return w.fitted }
+func (w WlsSummary) Response() []float64     { return w.response }
+func (w WlsSummary) Weights() []float64      { return w.weights }
+
+// SumOfSquares returns the weighted residual sum of squares, sum w_i e_i^2.
+func (w WlsSummary) SumOfSquares() float64 {
+	return sum(prod(w.weights, prod(w.residuals, w.residuals)))
+}
+
+// TotalSumofSquares returns sum w_i (y_i - ybar_w)^2, about the weighted mean.
+func (w WlsSummary) TotalSumofSquares() float64 {
+	ybar := sum(prod(w.weights, w.response)) / sum(w.weights)
+	r := subSlice(w.response, ybar)
+	return sum(prod(w.weights, prod(r, r)))
+}
+
+func (w WlsSummary) RSquared() float64 {
+	return 1 - w.SumOfSquares()/w.TotalSumofSquares()
+}
+
+func (w WlsSummary) String() string {
+	return fmt.Sprintf(`
+		Coefficients:
+		%v
+
+		Weighted RSS: %v
+		R-squared: %v`,
+		roundAll(w.betas),
+		round(w.SumOfSquares(), 3),
+		round(w.RSquared(), 3),
+	)
+}
+
+func (w *wlsTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n || len(w.weights) != n {
+		return nil, nil, DimensionError
+	}
+	for _, v := range w.weights {
+		if !(v > 0) {
+			return nil, nil, fmt.Errorf("weights must be positive")
+		}
+	}
+
+	data := withIntercept(x)
+	xw := data.Data()
+	yw := mat64.NewDense(n, 1, nil)
+	for i, v := range w.weights {
+		sw := math.Sqrt(v)
+		for j := 0; j < data.Cols(); j++ {
+			xw.Set(i, j, sw*xw.At(i, j))
+		}
+		yw.Set(i, 0, sw*y[i])
+	}
+
+	betaMat := &mat64.Dense{}
+	qr := &mat64.QR{}
+	qr.Factorize(xw)
+	if err := betaMat.SolveQR(qr, false, yw); err != nil {
+		return nil, nil, err
+	}
+	betas := mat64.Col(nil, 0, betaMat)
+
+	response := make([]float64, n)
+	copy(response, y)
+	fitted, residuals := linearFit(x, betas, response)
+
+	weights := make([]float64, n)
+	copy(weights, w.weights)
+
+	return &WLS{
+		betas: betas,
+	}, WlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		weights:   weights,
+		data:      data,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gonum/matrix/mat64"
+)
+
+var (
+	wlsX = [][]float64{{1.0}, {2.0}, {3.0}, {4.0}, {5.0}, {6.0}, {7.0}}
+	wlsY = []float64{2.1, 3.9, 6.3, 7.8, 10.4, 11.7, 14.6}
+	wlsW = []float64{1, 3, 2, 1, 4, 2, 1}
+)
+
+// integer weights are the same as repeating rows
+func TestWLSReplicated(t *testing.T) {
+	_, summary, err := NewWlsTrainer(wlsW).Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+
+	var rows [][]float64
+	var y []float64
+	for i, w := range wlsW {
+		for k := 0; k < int(w); k++ {
+			rows = append(rows, []float64{wlsX[i][0]})
+			y = append(y, wlsY[i])
+		}
+	}
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	assertNear(t, ols.Coefficients(), summary.Coefficients(), 1e-10)
+	assertNear(t, []float64{ols.SumOfSquares()}, []float64{summary.SumOfSquares()}, 1e-10)
+
+	_, _, err = NewWlsTrainer([]float64{1, 0, 1, 1, 1, 1, 1}).Train(NewDataFrame(wlsX), wlsY)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestWLSDiagnostics(t *testing.T) {
+	_, summary, err := NewWlsTrainer(wlsW).Train(NewDataFrame(wlsX), wlsY)
+	assert.Equal(t, nil, err)
+
+	// (Xt W X)^-1 directly
+	n := len(wlsY)
+	x := summary.Data().Data()
+	xtwx := mat64.NewDense(2, 2, nil)
+	for i := 0; i < n; i++ {
+		for a := 0; a < 2; a++ {
+			for b := 0; b < 2; b++ {
+				xtwx.Set(a, b, xtwx.At(a, b)+wlsW[i]*x.At(i, a)*x.At(i, b))
+			}
+		}
+	}
+	inv := &mat64.Dense{}
+	assert.Equal(t, nil, inv.Inverse(xtwx))
+
+	var (
+		s2       = summary.SumOfSquares() / float64(n-2)
+		e        = summary.Residuals()
+		h        = make([]float64, n)
+		cooks    = make([]float64, n)
+		students = make([]float64, n)
+	)
+	for i := 0; i < n; i++ {
+		row := mat64.Row(nil, i, x)
+		h[i] = wlsW[i] * (row[0]*row[0]*inv.At(0, 0) + 2*row[0]*row[1]*inv.At(0, 1) + row[1]*row[1]*inv.At(1, 1))
+		cooks[i] = wlsW[i] * e[i] * e[i] / (2 * s2) * h[i] / ((1 - h[i]) * (1 - h[i]))
+		students[i] = math.Sqrt(wlsW[i]) * e[i] / math.Sqrt(s2*(1-h[i]))
+	}
+
+	assertNear(t, h, LeveragePoints(summary), 1e-10)
+	assertNear(t, []float64{2}, []float64{sum(LeveragePoints(summary))}, 1e-10)
+	assertNear(t, cooks, CooksDistance(summary), 1e-10)
+	assertNear(t, students, StudentizedResiduals(summary), 1e-10)
+
+	vc, err := VarCov(summary)
+	assert.Equal(t, nil, err)
+	for a := 0; a < 2; a++ {
+		assertNear(t, multSlice(mat64.Row(nil, a, inv), s2), vc.GetRow(a), 1e-10)
+	}
+}